@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"drawl-server/game"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Drawings never change once stored, so clients can keep hold of them for as long as they like.
+const drawingCacheControl = "public, max-age=31536000, immutable"
+
+// How many rendered drawings are kept for ?format=png.
+const maxCachedRenders = 64
+
+// Drawings rendered for ?format=png, so asking for the same one again doesn't render it again. Games store a PNG of
+// every drawing sent as strokes (see Drawing.PNGRef), so this is only for links to the strokes themselves.
+type renderCache struct {
+	mu      sync.Mutex
+	renders map[string][]byte
+	// Oldest first, so there's something to drop once it's full.
+	order []string
+}
+
+func newRenderCache() *renderCache {
+	return &renderCache{renders: make(map[string][]byte)}
+}
+
+// The PNG of the drawing with this ref, rendering it if it isn't already cached.
+func (c *renderCache) render(ref string, strokes *game.StrokeDrawing) ([]byte, error) {
+	c.mu.Lock()
+	data, found := c.renders[ref]
+	c.mu.Unlock()
+	if found {
+		return data, nil
+	}
+	data, err := strokes.PNG()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, found = c.renders[ref]; !found {
+		if len(c.order) >= maxCachedRenders {
+			delete(c.renders, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.renders[ref] = data
+		c.order = append(c.order, ref)
+	}
+	return data, nil
+}
+
+func (s *Server) HandleGetDrawing(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		addXOriginHeader(w, r, s.handleGetDrawingGET)
+	case http.MethodOptions:
+		returnXOriginHeader(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// Drawings are served as they were stored, or ?format=png turns drawings sent as strokes into an image. Games keep
+// their own PNG of each drawing, which is the better one to ask for.
+func (s *Server) handleGetDrawingGET(w http.ResponseWriter, r *http.Request) {
+	ref := r.URL.Query().Get("ref")
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "png" {
+		http.Error(w, "format can only be png", http.StatusBadRequest)
+		return
+	}
+	if !game.ValidBlobRef(ref) {
+		http.Error(w, game.ErrInvalidBlobRef.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := s.Blobs.Get(ref)
+	if err == game.ErrBlobNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("ref", ref).Error("could not load drawing")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	contentType := http.DetectContentType(data)
+	etag := ref
+	strokes, err := game.ParseStrokeDrawing(data)
+	if err == nil {
+		contentType = "application/json"
+		if format == "png" {
+			data, err = s.renders.render(ref, strokes)
+			if err != nil {
+				log.WithError(err).WithField("ref", ref).Error("could not render drawing")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			contentType = "image/png"
+			etag = ref + "-png"
+		}
+	} else if format == "png" && contentType != "image/png" {
+		http.Error(w, "drawing can't be turned into a PNG", http.StatusNotAcceptable)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	// Never let a browser decide an old unchecked drawing is something more exciting, like HTML.
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", drawingCacheControl)
+	w.Header().Set("ETag", `"`+etag+`"`)
+	// Handles If-None-Match for us, so clients revalidating get a 304.
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
@@ -7,10 +7,10 @@ import (
 	"net/http"
 )
 
-func HandleNewGame(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandleNewGame(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		addXOriginHeader(w, r, handleNewGameGET)
+		addXOriginHeader(w, r, s.handleNewGameGET)
 	default:
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
@@ -23,19 +23,30 @@ type newGameResponse struct {
 	Player   *game.Player `json:"player"`
 }
 
-func handleNewGameGET(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleNewGameGET(w http.ResponseWriter, r *http.Request) {
+	settings, err := settingsFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	// Start websocket server for this newGame session.
-	newGame := game.NewGame()
+	newGame := game.NewGame(s.Archive, s.Blobs, settings)
 	// Save to "DB"
-	err := game.RegisterGame(newGame)
+	err = s.Games.RegisterGame(newGame)
 	if err != nil {
 		log.WithError(err).Error("could not register game")
 		http.Error(w, "could not create game", http.StatusInternalServerError)
 		return
 	}
-	player := newGame.NewPlayer()
+	player, err := newGame.NewPlayer()
+	if err != nil {
+		log.WithError(err).Error("could not add host to new game")
+		http.Error(w, "could not create game", http.StatusInternalServerError)
+		return
+	}
+	joinCode := newGame.CurrentJoinCode()
 	resp := newGameResponse{
-		JoinCode: newGame.JoinCode,
+		JoinCode: joinCode,
 		GameID:   newGame.ID,
 		Player:   player,
 	}
@@ -49,5 +60,5 @@ func handleNewGameGET(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.WithError(err).Error("could not write NewGame response")
 	}
-	log.WithField("joinCode", newGame.JoinCode).Debug("started new newGame")
+	log.WithField("joinCode", joinCode).Debug("started new newGame")
 }
@@ -7,10 +7,10 @@ import (
 	"net/http"
 )
 
-func HandleJoinGame(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandleJoinGame(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		addXOriginHeader(w, r, handleJoinGamePOST)
+		addXOriginHeader(w, r, s.handleJoinGamePOST)
 	case http.MethodOptions:
 		returnXOriginHeader(w, r)
 	default:
@@ -29,7 +29,7 @@ type joinGameResponse struct {
 	Player   *game.Player `json:"player"`
 }
 
-func handleJoinGamePOST(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleJoinGamePOST(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, 1024) // 12 bytes = 4 UTF-8 chars plus a bit for the JSON?
 	decoder := json.NewDecoder(r.Body)
 	var joinRequest joinGameRequest
@@ -39,13 +39,17 @@ func handleJoinGamePOST(w http.ResponseWriter, r *http.Request) {
 		log.WithError(err).Debug("invalid join game body contents")
 		return
 	}
-	game, err := game.FindGameByJoinCode(joinRequest.JoinCode)
+	game, err := s.Games.FindGameByJoinCode(joinRequest.JoinCode)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	player := game.NewPlayer()
-	resp := joinGameResponse{GameID: game.ID, JoinCode: game.JoinCode, Player: player}
+	player, err := game.NewPlayer()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	resp := joinGameResponse{GameID: game.ID, JoinCode: joinRequest.JoinCode, Player: player}
 	respJson, err := json.Marshal(resp)
 	if err != nil {
 		log.WithError(err).Error("could not marshal JoinGame response")
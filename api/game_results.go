@@ -1,15 +1,13 @@
 package api
 
 import (
-	"drawl-server/game"
-	"encoding/json"
 	"net/http"
 )
 
-func HandleGetGameResults(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandleGetGameResults(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		addXOriginHeader(w, r, handleGetGameResultsGET)
+		addXOriginHeader(w, r, s.handleGetGameResultsGET)
 	case http.MethodOptions:
 		returnXOriginHeader(w, r)
 	default:
@@ -18,19 +16,19 @@ func HandleGetGameResults(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func handleGetGameResultsGET(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleGetGameResultsGET(w http.ResponseWriter, r *http.Request) {
 	gameID := r.URL.Query().Get("game_id")
 	if gameID == "" {
 		http.Error(w, "missing game_id query parameter", http.StatusBadRequest)
 		return
 	}
-	matchingGame, err := game.FindGameByID(gameID)
+	matchingGame, err := s.findGame(gameID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 	// Serialize the whole game, oh boy
-	jsnData, err := json.Marshal(matchingGame.Players)
+	jsnData, err := matchingGame.ResultsJSON()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
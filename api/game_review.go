@@ -1,15 +1,13 @@
 package api
 
 import (
-	"drawl-server/game"
-	"encoding/json"
 	"net/http"
 )
 
-func HandleGetGameReview(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandleGetGameReview(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		addXOriginHeader(w, r, handleGetGameReviewGET)
+		addXOriginHeader(w, r, s.handleGetGameReviewGET)
 	case http.MethodOptions:
 		returnXOriginHeader(w, r)
 	default:
@@ -18,19 +16,19 @@ func HandleGetGameReview(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func handleGetGameReviewGET(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleGetGameReviewGET(w http.ResponseWriter, r *http.Request) {
 	gameID := r.URL.Query().Get("game_id")
 	if gameID == "" {
 		http.Error(w, "missing game_id query parameter", http.StatusBadRequest)
 		return
 	}
-	matchingGame, err := game.FindGameByID(gameID)
+	matchingGame, err := s.findGame(gameID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	// Serialize the whole game, oh boy
-	jsnData, err := json.Marshal(matchingGame)
+	// Serialize the whole game, drawings are only references so it's not so bad
+	jsnData, err := matchingGame.ReviewJSON()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
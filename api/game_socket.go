@@ -8,7 +8,7 @@ import (
 )
 
 // Enable connecting to the game's WebSocket hub.
-func HandleWS(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 	// Get Game ID and Player ID from URL, that should be something like /ws/<gameID>/<playerID>.
 	IDs := r.URL.Query()
 	gameID := IDs.Get("game_id")
@@ -17,7 +17,18 @@ func HandleWS(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing player or game ID", http.StatusBadRequest)
 		return
 	}
-	_, err := uuid.Parse(gameID)
+	// Clients can offer the protocol versions they speak, e.g. ?protocol=1,2
+	version, err := game.NegotiateProtocol(IDs.Get("protocol"))
+	if err == game.ErrLegacyProtocol {
+		log.WithField("playerID", playerID).Debug("client connected without choosing a protocol version")
+		game.RejectWs(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = uuid.Parse(gameID)
 	if err != nil {
 		log.WithError(err).Error("game UUID not found in WebSocket connection request")
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -29,34 +40,35 @@ func HandleWS(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	gameInstance, err := game.FindGameByID(gameID)
+	gameInstance, err := s.Games.FindGameByID(gameID)
 	if err != nil {
 		log.WithError(err).Error("game not found in WebSocket connection request")
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	// Check Player is in this game...
-	var player *game.Player = nil
-	for _, playr := range gameInstance.Players {
-		if playr.ID == playerID {
-			player = playr
-			break
-		}
+	// Check Player is in this game, or at least watching it...
+	spectating := false
+	player := gameInstance.FindPlayer(playerID)
+	if player == nil {
+		player = gameInstance.FindSpectator(playerID)
+		spectating = player != nil
 	}
 	if player == nil {
-		log.WithError(err).Error("player not found in this game during WebSocket connection request")
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		log.Error("player not found in this game during WebSocket connection request")
+		http.Error(w, "player not found in this game", http.StatusUnauthorized)
 		return
 	}
 	// Create a client and attach to the game hub.
-	game.ServeWs(gameInstance.Hub, player, w, r)
+	game.ServeWs(gameInstance.Hub, player, version, spectating, w, r)
 	IP := r.Header.Get("X-Forwarded-For")
 	if IP == "" {
 		IP = r.RemoteAddr
 	}
 	log.WithFields(log.Fields{
-		"IP":       IP,
-		"joinCode": gameInstance.JoinCode,
-		"playerID": player.ID,
+		"IP":        IP,
+		"joinCode":  gameInstance.CurrentJoinCode(),
+		"playerID":  player.ID,
+		"protocol":  version,
+		"spectator": spectating,
 	}).Debug("player connected")
 }
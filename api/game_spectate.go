@@ -0,0 +1,62 @@
+package api
+
+import (
+	"drawl-server/game"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// Watch a game by its join code, without playing in it.
+func (s *Server) HandleSpectateGame(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		addXOriginHeader(w, r, s.handleSpectateGamePOST)
+	case http.MethodOptions:
+		returnXOriginHeader(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type spectateGameResponse struct {
+	JoinCode  string       `json:"joinCode"`
+	GameID    string       `json:"gameID"`
+	Spectator *game.Player `json:"spectator"`
+}
+
+func (s *Server) handleSpectateGamePOST(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	var spectateRequest joinGameRequest
+	err := decoder.Decode(&spectateRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.WithError(err).Debug("invalid spectate game body contents")
+		return
+	}
+	// Spectators can still find the game once it's started, or locked.
+	game, err := s.Games.FindGameToSpectate(spectateRequest.JoinCode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	spectator, err := game.NewSpectator()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	resp := spectateGameResponse{GameID: game.ID, JoinCode: spectateRequest.JoinCode, Spectator: spectator}
+	respJson, err := json.Marshal(resp)
+	if err != nil {
+		log.WithError(err).Error("could not marshal SpectateGame response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(respJson)
+	if err != nil {
+		log.WithError(err).Error("could not write SpectateGame response")
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import "drawl-server/game"
+
+// Server holds what the HTTP handlers need to find games, so they don't rely on package state.
+type Server struct {
+	Games game.GameStore
+	// Finished games, may be nil if they aren't kept.
+	Archive game.GameArchive
+	// Where the games keep their drawings.
+	Blobs game.BlobStore
+	// Drawings already turned into PNGs for ?format=png.
+	renders *renderCache
+}
+
+func NewServer(games game.GameStore, archive game.GameArchive, blobs game.BlobStore) *Server {
+	return &Server{Games: games, Archive: archive, Blobs: blobs, renders: newRenderCache()}
+}
+
+// Find a game that is either still being played, or has finished and been archived.
+func (s *Server) findGame(gameID string) (*game.Game, error) {
+	activeGame, err := s.Games.FindGameByID(gameID)
+	if err == nil || s.Archive == nil {
+		return activeGame, err
+	}
+	return s.Archive.LoadGame(gameID)
+}
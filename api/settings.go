@@ -0,0 +1,73 @@
+package api
+
+import (
+	"drawl-server/game"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Game settings can be given as query parameters when creating a game, anything missing keeps its default.
+func settingsFromQuery(query url.Values) (game.GameSettings, error) {
+	settings := game.DefaultSettings()
+	intParams := map[string]*int{
+		"rounds":     &settings.Rounds,
+		"drawTime":   &settings.DrawTime,
+		"guessTime":  &settings.GuessTime,
+		"maxPlayers": &settings.MaxPlayers,
+		"promptTime": &settings.PromptTime,
+	}
+	for param, setting := range intParams {
+		if value := query.Get(param); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return settings, fmt.Errorf("invalid %v: %v", param, value)
+			}
+			*setting = parsed
+		}
+	}
+	if value := query.Get("contextWordChance"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return settings, fmt.Errorf("invalid contextWordChance: %v", value)
+		}
+		settings.ContextWordChance = parsed
+	}
+	if value := query.Get("seed"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return settings, fmt.Errorf("invalid seed: %v", value)
+		}
+		settings.Seed = parsed
+	}
+	if value := query.Get("wordPacks"); value != "" {
+		settings.WordPacks = strings.Split(value, ",")
+	}
+	boolParams := map[string]*bool{
+		"customPrompts":         &settings.CustomPrompts,
+		"uniquePrompts":         &settings.UniquePrompts,
+		"uniqueAcrossRematches": &settings.UniqueAcrossRematches,
+		"liveDrawings":          &settings.LiveDrawings,
+		"allowNSFW":             &settings.AllowNSFW,
+	}
+	for param, setting := range boolParams {
+		if value := query.Get(param); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return settings, fmt.Errorf("invalid %v: %v", param, value)
+			}
+			*setting = parsed
+		}
+	}
+	if value := query.Get("scoringMode"); value != "" {
+		settings.ScoringMode = game.ScoringMode(value)
+	}
+	if value := query.Get("endOn"); value != "" {
+		settings.EndOn = game.PlayKind(value)
+	}
+	if value := query.Get("playOrder"); value != "" {
+		settings.PlayOrder = game.PlayOrder(value)
+	}
+	return settings, settings.Validate()
+}
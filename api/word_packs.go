@@ -0,0 +1,33 @@
+package api
+
+import (
+	"drawl-server/game"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func (s *Server) HandleGetWordPacks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		addXOriginHeader(w, r, s.handleGetWordPacksGET)
+	case http.MethodOptions:
+		returnXOriginHeader(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+func (s *Server) handleGetWordPacksGET(w http.ResponseWriter, r *http.Request) {
+	jsnData, err := json.Marshal(game.ListWordPacks())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(jsnData)
+	if err != nil {
+		log.WithError(err).Error("could not write word packs response")
+	}
+}
@@ -3,67 +3,182 @@ package game
 import (
 	"errors"
 	"math/rand"
+	"sync"
 )
 
-var activeGames []*Game
-var joinCodes map[string]*Game
+// GameStore keeps track of the games currently being played, so they can be found by ID or join code.
+type GameStore interface {
+	// Register the game and assign it a join code.
+	RegisterGame(game *Game) error
+	UnregisterGame(gameID string)
+	FindGameByID(gameID string) (*Game, error)
+	// Find a game to join. Locked games can't be found.
+	FindGameByJoinCode(joinCode string) (*Game, error)
+	// Find a game to watch. Games can be watched by their join code for as long as they're registered.
+	FindGameToSpectate(joinCode string) (*Game, error)
+	// Stop the game being joinable. It can still be spectated.
+	RemoveGameJoinCode(game *Game)
+	// Give a registered game a fresh join code, so it can be joined again. Called from inside the game loop.
+	AssignJoinCode(game *Game) error
+	// Register a game brought back from a snapshot, keeping its join code if it is still joinable.
+	RestoreGame(game *Game) error
+}
 
-func init() {
-	activeGames = make([]*Game, 0)
-	joinCodes = make(map[string]*Game)
+// MemoryStore is a GameStore that only lives as long as the process. Safe for concurrent use.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	games map[string]*Game
+	// Games that can be joined.
+	joinCodes map[string]*Game
+	// Every game's join code, joinable or not, for spectators.
+	spectateCodes map[string]*Game
 }
 
-// Register the game and create a join code for it
-func RegisterGame(game *Game) error {
-	joinCode, err := generateJoinCode()
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		games:         make(map[string]*Game),
+		joinCodes:     make(map[string]*Game),
+		spectateCodes: make(map[string]*Game),
+	}
+}
+
+func (s *MemoryStore) RegisterGame(game *Game) error {
+	s.mu.Lock()
+	if _, found := s.games[game.ID]; found {
+		s.mu.Unlock()
+		return errors.New("game already registered")
+	}
+	joinCode, err := s.generateJoinCode()
 	if err != nil {
+		s.mu.Unlock()
 		return err
 	}
-	activeGames = append(activeGames, game)
-	game.JoinCode = joinCode
-	joinCodes[joinCode] = game
+	s.games[game.ID] = game
+	s.joinCodes[joinCode] = game
+	s.spectateCodes[joinCode] = game
+	s.mu.Unlock()
+	// The game is already running, so its own loop fills these in. Not while holding the lock, the loop may be
+	// waiting on it.
+	err = game.exec(func() {
+		game.JoinCode = joinCode
+		game.store = s
+	})
+	if err != nil {
+		s.mu.Lock()
+		delete(s.games, game.ID)
+		delete(s.joinCodes, joinCode)
+		delete(s.spectateCodes, joinCode)
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *MemoryStore) RestoreGame(game *Game) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.games[game.ID]; found {
+		return errors.New("game already registered")
+	}
+	if _, taken := s.spectateCodes[game.JoinCode]; taken || game.JoinCode == "" {
+		joinCode, err := s.generateJoinCode()
+		if err != nil {
+			return err
+		}
+		game.JoinCode = joinCode
+	}
+	s.spectateCodes[game.JoinCode] = game
+	if game.Stage == GAME_STARTING {
+		s.joinCodes[game.JoinCode] = game
+	}
+	game.store = s
+	s.games[game.ID] = game
 	return nil
 }
 
-func UnregisterGame(gameID string) {
-	if len(activeGames) < 2 {
-		activeGames = make([]*Game, 0)
+func (s *MemoryStore) UnregisterGame(gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	game, found := s.games[gameID]
+	if !found {
 		return
 	}
-	for i, game := range activeGames {
-		if game.ID == gameID {
-			activeGames[i] = activeGames[len(activeGames)-1]
-			activeGames = activeGames[:len(activeGames)-1]
-		}
+	delete(s.games, gameID)
+	s.releaseJoinCode(game)
+}
+
+// Caller must hold the write lock.
+func (s *MemoryStore) releaseJoinCode(game *Game) {
+	if s.joinCodes[game.JoinCode] == game {
+		delete(s.joinCodes, game.JoinCode)
+	}
+	if s.spectateCodes[game.JoinCode] == game {
+		delete(s.spectateCodes, game.JoinCode)
 	}
 }
 
-func FindGameByID(gameID string) (*Game, error) {
-	for _, game := range activeGames {
-		if game.ID == gameID {
-			return game, nil
-		}
+func (s *MemoryStore) FindGameByID(gameID string) (*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	game, found := s.games[gameID]
+	if !found {
+		return nil, errors.New("game not found")
 	}
-	return nil, errors.New("game not found")
+	return game, nil
 }
 
-func FindGameByJoinCode(joinCode string) (*Game, error) {
-	game, found := joinCodes[joinCode]
+func (s *MemoryStore) FindGameByJoinCode(joinCode string) (*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	game, found := s.joinCodes[joinCode]
 	if !found {
 		return nil, errors.New("game not found, or no longer joinable")
 	}
+	if game.IsLocked() {
+		return nil, errors.New("game is locked")
+	}
+	return game, nil
+}
+
+func (s *MemoryStore) FindGameToSpectate(joinCode string) (*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	game, found := s.spectateCodes[joinCode]
+	if !found {
+		return nil, errors.New("game not found")
+	}
 	return game, nil
 }
 
-func RemoveGameJoinCode(game *Game) {
-	delete(joinCodes, game.JoinCode)
+func (s *MemoryStore) RemoveGameJoinCode(game *Game) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.joinCodes[game.JoinCode] == game {
+		delete(s.joinCodes, game.JoinCode)
+	}
+}
+
+func (s *MemoryStore) AssignJoinCode(game *Game) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.games[game.ID] != game {
+		return errors.New("game not registered")
+	}
+	s.releaseJoinCode(game)
+	joinCode, err := s.generateJoinCode()
+	if err != nil {
+		return err
+	}
+	game.JoinCode = joinCode
+	s.joinCodes[joinCode] = game
+	s.spectateCodes[joinCode] = game
+	return nil
 }
 
-// Generate a random string of A-Z chars with len 4
-func generateJoinCode() (string, error) {
+// Generate a random string of A-Z chars with len 4. Caller must hold the write lock.
+func (s *MemoryStore) generateJoinCode() (string, error) {
 	for i := 0; i < 100; i++ {
 		code := randomCode()
-		if _, found := joinCodes[code]; !found {
+		if _, found := s.spectateCodes[code]; !found {
 			return code, nil
 		}
 	}
@@ -0,0 +1,139 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// GameArchive keeps finished games around once they've left the GameStore, so they can still be reviewed.
+// It also holds snapshots of running games, so they can be picked back up if the server restarts.
+type GameArchive interface {
+	SaveGame(game *Game) error
+	LoadGame(gameID string) (*Game, error)
+	SaveSnapshot(game *Game) error
+	DeleteSnapshot(gameID string) error
+	LoadSnapshots() ([]*Game, error)
+}
+
+var errGameNotArchived = errors.New("game not found in archive")
+
+// FileArchive stores each finished game as a JSON file in a directory, with snapshots of running games in a
+// subdirectory.
+type FileArchive struct {
+	dir string
+}
+
+const snapshotDir = "running"
+
+// Added to snapshots that couldn't be read.
+const corruptSuffix = ".corrupt"
+
+func NewFileArchive(dir string) (*FileArchive, error) {
+	err := os.MkdirAll(filepath.Join(dir, snapshotDir), 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &FileArchive{dir: dir}, nil
+}
+
+func (a *FileArchive) SaveGame(game *Game) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.gamePath(game.ID), data)
+}
+
+func (a *FileArchive) LoadGame(gameID string) (*Game, error) {
+	// Game IDs come straight from query strings, don't let them wander around the file system.
+	if _, err := uuid.Parse(gameID); err != nil {
+		return nil, errGameNotArchived
+	}
+	data, err := ioutil.ReadFile(a.gamePath(gameID))
+	if os.IsNotExist(err) {
+		return nil, errGameNotArchived
+	}
+	if err != nil {
+		return nil, err
+	}
+	game := &Game{}
+	err = json.Unmarshal(data, game)
+	if err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func (a *FileArchive) SaveSnapshot(game *Game) error {
+	data, err := json.Marshal(newGameSnapshot(game))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.snapshotPath(game.ID), data)
+}
+
+func (a *FileArchive) DeleteSnapshot(gameID string) error {
+	err := os.Remove(a.snapshotPath(gameID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (a *FileArchive) LoadSnapshots() ([]*Game, error) {
+	paths, err := filepath.Glob(filepath.Join(a.dir, snapshotDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	games := make([]*Game, 0, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			var snapshot gameSnapshot
+			err = json.Unmarshal(data, &snapshot)
+			if err == nil {
+				games = append(games, snapshot.toGame())
+				continue
+			}
+		}
+		// One broken snapshot shouldn't stop every other game coming back. Move it out of the way so it can be looked
+		// at, without tripping us up on every restart.
+		logger := log.WithError(err).WithField("path", path)
+		logger.Error("could not read snapshot, skipping it")
+		if renameErr := os.Rename(path, path+corruptSuffix); renameErr != nil {
+			logger.WithError(renameErr).Error("could not move unreadable snapshot aside")
+		}
+	}
+	return games, nil
+}
+
+func (a *FileArchive) gamePath(gameID string) string {
+	return filepath.Join(a.dir, gameID+".json")
+}
+
+func (a *FileArchive) snapshotPath(gameID string) string {
+	return filepath.Join(a.dir, snapshotDir, gameID+".json")
+}
+
+// Write to a temporary file and rename it over the target, so a crash never leaves half a game on disk.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
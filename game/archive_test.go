@@ -0,0 +1,94 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchivedGamesComeBackIntact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive, err := NewFileArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newTestGame(t, DefaultSettings(), 2)
+	g.ID = "11111111-1111-1111-1111-111111111111"
+	g.Stage = GAME_ENDED
+	g.Limit = 2
+	g.Players[0].Points = 3
+	for i, order := range [][]*Player{{g.Players[0], g.Players[1]}, {g.Players[1], g.Players[0]}} {
+		g.Journeys = append(g.Journeys, &WordJourney{Order: order, Plays: []GamePlay{
+			&Word{Word: "Cat"},
+			&Drawing{Ref: blobRef([]byte{byte(i)}), Format: DRAWING_STROKES, Player: order[0], PNGRef: blobRef([]byte("png"))},
+			&Word{Word: "Dog", Player: order[1]},
+		}})
+	}
+	if err = archive.SaveGame(g); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := archive.LoadGame(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := json.Marshal(g)
+	reloaded, _ := json.Marshal(loaded)
+	if string(saved) != string(reloaded) {
+		t.Errorf("archived game came back as\n%s\nwant\n%s", reloaded, saved)
+	}
+	for i, journey := range loaded.Journeys {
+		if _, isDrawing := journey.Plays[1].(*Drawing); !isDrawing {
+			t.Errorf("journey %v's drawing came back as %#v", i, journey.Plays[1])
+		}
+		if guess, isWord := journey.Plays[2].(*Word); !isWord || guess.Player.ID != journey.Order[1].ID {
+			t.Errorf("journey %v's guess came back as %#v", i, journey.Plays[2])
+		}
+	}
+
+	for _, gameID := range []string{"../running/" + g.ID, "not-a-game", ""} {
+		if _, err = archive.LoadGame(gameID); err != errGameNotArchived {
+			t.Errorf("loading %q gave %v", gameID, err)
+		}
+	}
+}
+
+func TestLoadSnapshotsSkipsUnreadableOnes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive, err := NewFileArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newTestGame(t, DefaultSettings(), 2)
+	g.ID = "11111111-1111-1111-1111-111111111111"
+	err = archive.SaveSnapshot(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, snapshotDir, "22222222-2222-2222-2222-222222222222.json")
+	err = ioutil.WriteFile(broken, []byte("{not json"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	games, err := archive.LoadSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 || games[0].ID != g.ID {
+		t.Fatalf("got %v games back, want just %v", len(games), g.ID)
+	}
+	if _, err := os.Stat(broken + corruptSuffix); err != nil {
+		t.Errorf("broken snapshot wasn't moved aside: %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package game
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BlobStore keeps drawings out of the Game, so games (and their reviews) only carry references to them. Blobs are
+// addressed by their content, so storing the same drawing twice gives the same reference, and a reference always
+// means the same bytes.
+type BlobStore interface {
+	// Store the data, returning the reference to fetch it back with.
+	Put(data []byte) (string, error)
+	Get(ref string) ([]byte, error)
+}
+
+var (
+	ErrBlobNotFound   = errors.New("drawing not found")
+	ErrInvalidBlobRef = errors.New("invalid drawing reference")
+)
+
+// References are the hex SHA-256 of the blob.
+func blobRef(data []byte) string {
+	return sha256Hex(data)
+}
+
+// Check a reference looks like one we'd have handed out, before it gets anywhere near a file path or URL.
+func ValidBlobRef(ref string) bool {
+	if len(ref) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range ref {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryBlobStore keeps every blob in memory, and never forgets any. Handy for development and tests.
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: make(map[string][]byte)}
+}
+
+func (s *MemoryBlobStore) Put(data []byte) (string, error) {
+	ref := blobRef(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.blobs[ref]; !found {
+		s.blobs[ref] = append([]byte{}, data...)
+	}
+	return ref, nil
+}
+
+func (s *MemoryBlobStore) Get(ref string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, found := s.blobs[ref]
+	if !found {
+		return nil, ErrBlobNotFound
+	}
+	return data, nil
+}
+
+// FileBlobStore keeps each blob as a file in a directory, spread over subdirectories by the start of their reference
+// so no one directory gets too big.
+type FileBlobStore struct {
+	dir string
+}
+
+func NewFileBlobStore(dir string) (*FileBlobStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBlobStore{dir: dir}, nil
+}
+
+func (s *FileBlobStore) Put(data []byte) (string, error) {
+	ref := blobRef(data)
+	path := s.blobPath(ref)
+	if _, err := os.Stat(path); err == nil {
+		// Already got it, and it can't have changed.
+		return ref, nil
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return "", err
+	}
+	return ref, writeFileAtomic(path, data)
+}
+
+func (s *FileBlobStore) Get(ref string) ([]byte, error) {
+	if !ValidBlobRef(ref) {
+		return nil, ErrInvalidBlobRef
+	}
+	data, err := ioutil.ReadFile(s.blobPath(ref))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+func (s *FileBlobStore) blobPath(ref string) string {
+	return filepath.Join(s.dir, ref[:2], ref)
+}
+
+// How many times storing a blob is tried before giving up on it.
+const blobPutAttempts = 3
+
+// Store a drawing without holding up the game loop. Blobs are addressed by their content, so the reference is known
+// straight away and the play can be recorded while the drawing is stored in the background. It's kept to hand for
+// sending on to the next player.
+func (g *Game) putDrawing(format DrawingFormat, data []byte) string {
+	ref := blobRef(data)
+	g.cacheDrawing(ref, drawingDataFor(format, data))
+	g.uploads++
+	go func() {
+		err := putBlob(g.blobs, data)
+		g.exec(func() {
+			g.uploadFinished(ref, err)
+		})
+	}()
+	return ref
+}
+
+// Render a drawing sent as strokes to a PNG in the background, and store that too, so it never needs rendering again
+// when someone wants an image of it.
+func (g *Game) renderDrawing(drawing *Drawing, data []byte) {
+	ref := drawing.Ref
+	g.uploads++
+	go func() {
+		var pngData []byte
+		strokes, err := ParseStrokeDrawing(data)
+		if err == nil {
+			pngData, err = strokes.PNG()
+		}
+		if err == nil {
+			err = putBlob(g.blobs, pngData)
+		}
+		g.exec(func() {
+			if err == nil {
+				drawing.PNGRef = blobRef(pngData)
+			}
+			g.uploadFinished(ref, err)
+		})
+	}()
+}
+
+// Called in the game loop once a background upload is done with, however it went.
+func (g *Game) uploadFinished(ref string, err error) {
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"gameID": g.ID, "ref": ref}).Error("could not store drawing")
+	}
+	g.uploads--
+	if g.uploads == 0 {
+		waiting := g.afterUploads
+		g.afterUploads = nil
+		for _, f := range waiting {
+			f()
+		}
+	}
+}
+
+func putBlob(blobs BlobStore, data []byte) error {
+	var err error
+	for attempt := 1; attempt <= blobPutAttempts; attempt++ {
+		if _, err = blobs.Put(data); err == nil {
+			return nil
+		}
+		if attempt < blobPutAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	return err
+}
+
+// Run f once every drawing has been stored, so anyone told to fetch them can. Straight away if they already have.
+func (g *Game) whenStored(f func()) {
+	if g.uploads == 0 {
+		f()
+		return
+	}
+	g.afterUploads = append(g.afterUploads, f)
+}
+
+// Get hold of the drawing itself, for sending on to the next player, and give it to f in the game loop. It's usually
+// to hand, otherwise it's fetched from the blob store in the background.
+func (g *Game) withDrawingData(drawing *Drawing, f func(data string)) {
+	ref, format := drawing.Ref, drawing.Format
+	if ref == "" {
+		// A blank drawing, there is nothing to fetch.
+		f("")
+		return
+	}
+	if data, found := g.drawingCache[ref]; found {
+		f(data)
+		return
+	}
+	go func() {
+		var drawingData string
+		data, err := g.blobs.Get(ref)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{"gameID": g.ID, "ref": ref}).Error("could not load drawing")
+		} else {
+			drawingData = drawingDataFor(format, data)
+		}
+		g.exec(func() {
+			if err == nil {
+				g.cacheDrawing(ref, drawingData)
+			}
+			f(drawingData)
+		})
+	}()
+}
+
+func (g *Game) cacheDrawing(ref string, data string) {
+	if g.drawingCache == nil {
+		g.drawingCache = make(map[string]string)
+	}
+	g.drawingCache[ref] = data
+}
+
+// Forget the drawings that won't be sent on again, keeping the ones the current round is played on.
+func (g *Game) pruneDrawingCache() {
+	cache := make(map[string]string)
+	if g.Stage == GAME_RUNNING {
+		for _, journey := range g.Journeys {
+			if len(journey.Plays) <= g.Round {
+				continue
+			}
+			if drawing, isDrawing := journey.Plays[g.Round].(*Drawing); isDrawing {
+				if data, found := g.drawingCache[drawing.Ref]; found {
+					cache[drawing.Ref] = data
+				}
+			}
+		}
+	}
+	g.drawingCache = cache
+}
+
+// A stored drawing the way it's sent on to the next player.
+func drawingDataFor(format DrawingFormat, data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	switch format {
+	case DRAWING_PNG:
+		return pngDataURL(data)
+	case DRAWING_STROKES:
+		return string(data)
+	}
+	return ""
+}
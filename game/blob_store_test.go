@@ -0,0 +1,101 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// A blob store that doesn't store anything until it's told it can.
+type stalledBlobStore struct {
+	*MemoryBlobStore
+	release chan struct{}
+}
+
+func (s *stalledBlobStore) Put(data []byte) (string, error) {
+	<-s.release
+	return s.MemoryBlobStore.Put(data)
+}
+
+func (s *stalledBlobStore) Get(ref string) ([]byte, error) {
+	<-s.release
+	return s.MemoryBlobStore.Get(ref)
+}
+
+// Run f in the game loop, failing if the loop is too busy to get to it.
+func execWithin(t *testing.T, g *Game, f func()) {
+	t.Helper()
+	finished := make(chan struct{})
+	go func() {
+		g.exec(f)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("game loop is stuck")
+	}
+}
+
+func TestDrawingsAreStoredOffTheGameLoop(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	blobs := &stalledBlobStore{MemoryBlobStore: NewMemoryBlobStore(), release: make(chan struct{})}
+	g.blobs = blobs
+	go g.run()
+
+	var ref string
+	execWithin(t, g, func() {
+		ref = g.putDrawing(DRAWING_STROKES, []byte(`{"width":1,"height":1,"strokes":[]}`))
+	})
+	stored := false
+	execWithin(t, g, func() {
+		g.whenStored(func() { stored = true })
+	})
+	var data string
+	execWithin(t, g, func() {
+		// Still to hand for the next player while it's being stored.
+		g.withDrawingData(&Drawing{Ref: ref, Format: DRAWING_STROKES}, func(d string) { data = d })
+	})
+	if data == "" {
+		t.Error("drawing being stored isn't to hand")
+	}
+	if stored {
+		t.Error("drawing counted as stored before the store had it")
+	}
+
+	close(blobs.release)
+	stillStoring := true
+	for deadline := time.Now().Add(time.Second); stillStoring && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		execWithin(t, g, func() { stillStoring = !stored })
+	}
+	if stillStoring {
+		t.Fatal("never heard the drawing had been stored")
+	}
+	if _, err := blobs.MemoryBlobStore.Get(ref); err != nil {
+		t.Errorf("drawing wasn't stored: %v", err)
+	}
+}
+
+func TestDrawingsAreFetchedOffTheGameLoop(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	blobs := &stalledBlobStore{MemoryBlobStore: NewMemoryBlobStore(), release: make(chan struct{})}
+	g.blobs = blobs
+	ref, _ := blobs.MemoryBlobStore.Put([]byte(`{"width":1,"height":1,"strokes":[]}`))
+	go g.run()
+
+	fetched := make(chan string, 1)
+	execWithin(t, g, func() {
+		g.withDrawingData(&Drawing{Ref: ref, Format: DRAWING_STROKES}, func(d string) { fetched <- d })
+	})
+	// Nothing else should have to wait for it.
+	execWithin(t, g, func() {})
+	close(blobs.release)
+	select {
+	case data := <-fetched:
+		if data == "" {
+			t.Error("fetched drawing is empty")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drawing never arrived")
+	}
+}
@@ -21,8 +21,10 @@ const (
 	pongWait = 30 * time.Second
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
+	// Time allowed for the game to take a message from the peer.
+	forwardWait = time.Second
 	// Maximum message size allowed from peer.
-	// TODO: Optimise drawing size, these things get biiiiig!
+	// Drawings sent as strokes are small, but images still get biiiiig!
 	maxMessageSize = 250 * 1024
 )
 
@@ -47,6 +49,10 @@ var upgrader = websocket.Upgrader{
 type Client struct {
 	hub    *GameHub
 	player *Player
+	// Protocol version agreed when connecting.
+	version int
+	// Spectators get broadcasts, but can't play.
+	spectating bool
 	// The websocket connection.
 	conn *websocket.Conn
 	// Buffered channel of outbound messages.
@@ -75,16 +81,41 @@ func (c *Client) read() {
 			break
 		}
 		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
-		forwardMsg := &IncomingMessage{
-			Player:  c.player,
-			Message: message,
-		}
-		select {
-		case c.hub.incomingMessages <- forwardMsg:
-		default:
-			log.Error("could not forward incoming message")
-		}
+		c.forward(message)
+	}
+}
 
+// Pass a message from the player on to the game. If the game can't take it in time the player is told, rather than
+// it quietly going missing, which matters most for strokes as they aren't acknowledged.
+func (c *Client) forward(message []byte) {
+	forwardMsg := &IncomingMessage{
+		Player:    c.player,
+		Spectator: c.spectating,
+		Version:   c.version,
+		Message:   message,
+	}
+	// Anything slow about the message is done here, rather than holding up the game.
+	forwardMsg.decode()
+	select {
+	case c.hub.incomingMessages <- forwardMsg:
+		return
+	case <-time.After(forwardWait):
+	case <-c.hub.gameDone:
+	}
+	log.WithField("playerID", c.player.ID).Error("could not forward incoming message")
+	refusal, err := encodeMessage("error", ErrorMessage{
+		RequestID: forwardMsg.envelope.ID,
+		Code:      ErrCodeBusy,
+		Message:   "the game couldn't keep up, send that again",
+	})
+	if err != nil {
+		return
+	}
+	// Through the hub, which owns the send channel and may have closed it.
+	select {
+	case c.hub.messages <- &GameMessage{Target: c.player, Message: &refusal}:
+	default:
+		log.Error("could not tell player their message was dropped")
 	}
 }
 
@@ -133,14 +164,42 @@ func (c *Client) write() {
 	}
 }
 
-// ServeWs handles websocket requests from the player.
-func ServeWs(hub *GameHub, player *Player, w http.ResponseWriter, r *http.Request) {
+// Accept the connection only to close it straight away, explaining why. Browsers don't let WebSocket clients see why
+// an upgrade failed, but they do get the close reason.
+func RejectWs(w http.ResponseWriter, r *http.Request, reason string) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, player: player, send: make(chan *GameMessage, 256)}
+	defer conn.Close()
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseProtocolError, reason)
+	err = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(writeWait))
+	if err != nil {
+		log.WithError(err).Debug("could not send WebSocket close reason")
+	}
+}
+
+// ServeWs handles websocket requests from the player (or spectator), who will be spoken to using the given protocol
+// version.
+func ServeWs(hub *GameHub, player *Player, version int, spectating bool, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	client := &Client{
+		hub:        hub,
+		conn:       conn,
+		player:     player,
+		version:    version,
+		spectating: spectating,
+		send:       make(chan *GameMessage, 256),
+	}
+	hello, err := encodeMessage("hello", HelloMessage{Version: version, PlayerID: player.ID})
+	if err == nil {
+		client.send <- &GameMessage{Target: player, Message: &hello}
+	}
 	client.hub.register <- client
 
 	go client.write()
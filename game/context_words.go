@@ -0,0 +1,121 @@
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Context words are templates filled in with details of the game, like "A Portrait of {player}". The placeholders
+// they can use are:
+//
+//	{player}       another player's name
+//	{otherPlayer}  a different other player's name
+//	{host}         the host's name
+//	{playerCount}  how many people are playing
+//	{date}         the date the game started, e.g. "25 December"
+//	{season}       the season the game started in
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+var knownPlaceholders = map[string]bool{
+	"player":      true,
+	"otherPlayer": true,
+	"host":        true,
+	"playerCount": true,
+	"date":        true,
+	"season":      true,
+}
+
+// Check a template only uses placeholders we know how to fill in.
+func validateContextTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("empty context template")
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !knownPlaceholders[match[1]] {
+			return fmt.Errorf("unknown placeholder {%v} in %q", match[1], template)
+		}
+	}
+	// Anything left over is a brace that isn't part of a placeholder.
+	if strings.ContainsAny(placeholderPattern.ReplaceAllString(template, ""), "{}") {
+		return fmt.Errorf("unmatched brace in %q", template)
+	}
+	return nil
+}
+
+// How many players other than the one it's for the template needs.
+func otherPlayersNeeded(template string) int {
+	if strings.Contains(template, "{otherPlayer}") {
+		return 2
+	}
+	if strings.Contains(template, "{player}") {
+		return 1
+	}
+	return 0
+}
+
+// Context words use other player's names, amongst other things. Returns false if none of the chosen packs have a
+// template that works for this game.
+func generateContextWord(g *Game, player *Player) (string, bool) {
+	otherPlayers := make([]*Player, 0)
+	for _, playa := range g.Players {
+		if player.ID != playa.ID {
+			otherPlayers = append(otherPlayers, playa)
+		}
+	}
+	templates, weights := contextFromPacks(g.Settings.WordPacks, g.Settings.AllowNSFW)
+	usableTemplates := make([]string, 0, len(templates))
+	usableWeights := make([]float64, 0, len(weights))
+	for i, template := range templates {
+		if otherPlayersNeeded(template) <= len(otherPlayers) {
+			usableTemplates = append(usableTemplates, template)
+			usableWeights = append(usableWeights, weights[i])
+		}
+	}
+	if len(usableTemplates) == 0 {
+		return "", false
+	}
+	template := pickWeightedWord(bagPoolContext, usableTemplates, usableWeights, g.WordBag, g.rng)
+	// Shuffle the others so {player} and {otherPlayer} are two different people.
+	for i := len(otherPlayers) - 1; i > 0; i-- {
+		j := g.rng.Intn(i + 1)
+		otherPlayers[i], otherPlayers[j] = otherPlayers[j], otherPlayers[i]
+	}
+	started := g.StartedAt
+	values := map[string]string{
+		"playerCount": strconv.Itoa(len(g.Players)),
+		"date":        started.Format("2 January"),
+		"season":      season(started),
+	}
+	if len(otherPlayers) > 0 {
+		values["player"] = otherPlayers[0].Name
+	}
+	if len(otherPlayers) > 1 {
+		values["otherPlayer"] = otherPlayers[1].Name
+	}
+	if host, found := g.PlayerMap[g.HostID]; found {
+		values["host"] = host.Name
+	} else {
+		values["host"] = "The Host"
+	}
+	word := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[strings.Trim(placeholder, "{}")]
+	})
+	return word, true
+}
+
+// Seasons in the northern hemisphere, where most of us are.
+func season(now time.Time) string {
+	switch now.Month() {
+	case time.December, time.January, time.February:
+		return "Winter"
+	case time.March, time.April, time.May:
+		return "Spring"
+	case time.June, time.July, time.August:
+		return "Summer"
+	default:
+		return "Autumn"
+	}
+}
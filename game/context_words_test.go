@@ -0,0 +1,52 @@
+package game
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContextWordsUseTheGameStartDate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer LoadWordPacks(dir + "-missing")
+	pack := "words: [Cat]\ncontext: [\"{player} on {date} in {season}\"]\n"
+	if err = ioutil.WriteFile(filepath.Join(dir, "dates.yaml"), []byte(pack), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = LoadWordPacks(dir); err != nil {
+		t.Fatal(err)
+	}
+	settings := DefaultSettings()
+	settings.WordPacks = []string{"dates"}
+	g := newTestGame(t, settings, 2)
+	g.StartedAt = time.Date(2019, time.July, 4, 12, 0, 0, 0, time.UTC)
+
+	word, ok := generateContextWord(g, g.Players[0])
+	if !ok {
+		t.Fatal("no context word")
+	}
+	if want := "Player 1 on 4 July in Summer"; word != want {
+		t.Errorf("got %q, want %q", word, want)
+	}
+
+	// The start date goes in the snapshot, so a restored game still uses it.
+	data, err := json.Marshal(newGameSnapshot(g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &gameSnapshot{}
+	if err = json.Unmarshal(data, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored := snapshot.toGame()
+	if word, _ = generateContextWord(restored, restored.Players[0]); word != "Player 1 on 4 July in Summer" {
+		t.Errorf("restored game gave %q", word)
+	}
+}
@@ -2,13 +2,16 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
-	"strings"
+	"math/rand"
 	"time"
 )
 
+// A Game is run as a single goroutine (see run), which owns all of its state. Anything outside that goroutine, like
+// the HTTP handlers, has to go through the exported methods that hand work to it.
 type Game struct {
 	ID              string                `json:"gameID"`
 	Hub             *GameHub              `json:"-"`
@@ -21,251 +24,469 @@ type Game struct {
 	Round           int                   `json:"round"`
 	Limit           int                   `json:"limit"`
 	GameEvents      chan *IncomingMessage `json:"-"`
-	// Send events when game events occur to check if the round is complete.
-	GameProgressChecker chan struct{} `json:"-"`
 	// Notification from the Hub of players reconnecting, so we can send their most recent update.
 	ReconnectionChannel chan *Player `json:"-"`
+	// Notification from the Hub of players connecting and disconnecting.
+	PresenceChannel chan *PresenceUpdate `json:"-"`
+	Settings        GameSettings         `json:"settings"`
+	// When the current round will be filled in and moved on, if it has a time limit.
+	Deadline time.Time `json:"deadline"`
+	// When the game was started. Context words about the date go by this rather than the clock, so they come out the
+	// same when a game is restored or replayed.
+	StartedAt time.Time `json:"startedAt"`
+	// The player in charge of the lobby.
+	HostID string `json:"hostID"`
+	// Everything random about the game (apart from the join code) comes from rng, seeded with this. The same seed
+	// and the same plays give the same game.
+	Seed int64 `json:"seed"`
+	rng  *rand.Rand
+	// Where rng has got to, so a restored game can carry on from there rather than replay what it has already drawn.
+	rngSource *countingSource
+	// Starting words already used, when they shouldn't repeat.
+	WordBag *WordBag `json:"wordBag,omitempty"`
+	// Starting prompts written by each player, by ID, when they get to write their own.
+	Prompts map[string]string `json:"prompts,omitempty"`
+	// People watching the game, by ID. They aren't players, and don't play.
+	Spectators map[string]*Player `json:"spectators,omitempty"`
+	// Journeys from earlier games played by this lobby, oldest first.
+	PreviousJourneys [][]*WordJourney `json:"previousJourneys,omitempty"`
+	// Who each player has awarded points to, by ID, when they can only award each player once.
+	Awards map[string][]string `json:"awards,omitempty"`
+	// Set while the host has stopped anyone else joining, only read or written atomically.
+	locked int32
+	// IDs of players (and spectators) with a connection open right now.
+	connected map[string]bool
+	// Fires once the host has been gone long enough to be replaced.
+	hostTimer *time.Timer
+	// Work from other goroutines to be done inside the game loop.
+	actions chan func()
+	// Closed once the game loop has stopped.
+	done chan struct{}
+	// Fires when the current round runs out of time.
+	roundTimer *time.Timer
+	// The store this game was registered with, if any.
+	store GameStore
+	// Where the game is kept once it has ended, if anywhere.
+	archive GameArchive
+	// Where the drawings are kept, the journeys only hold references to them.
+	blobs BlobStore
+	// Drawings that are about to be sent on to the next players, by reference, so they needn't be fetched back.
+	drawingCache map[string]string
+	// How many drawings are still being stored, and what's waiting for them to be.
+	uploads      int
+	afterUploads []func()
+	// Drawings being streamed in this round, by player ID.
+	recordings map[string]*strokeRecording
+	// Strokes waiting to be relayed to whoever is watching, and when they'll go.
+	liveStrokes []LiveStroke
+	liveTimer   *time.Timer
 }
 
-// Start a new game up, and return the UUID and join code.
-func NewGame() *Game {
-	game := Game{}
-	game.GameEvents = make(chan *IncomingMessage, 32)
-	game.ReconnectionChannel = make(chan *Player, 10)
+// Start a new game up, and return the UUID and join code. Finished games are saved to the archive if it isn't nil.
+// Drawings go in the blob store. The settings should already have been validated.
+func NewGame(archive GameArchive, blobs BlobStore, settings GameSettings) *Game {
+	game := Game{archive: archive, blobs: blobs, Settings: settings}
 	ID, err := uuid.NewRandom()
 	if err != nil {
 		log.Fatal("Entropy problems, oh my")
 	}
 	game.ID = ID.String()
-	// Start websocket server
-	hub := newHub(game.GameEvents, game.ReconnectionChannel)
-	go hub.run()
-	game.Hub = hub
+	game.Seed = settings.Seed
+	if game.Seed == 0 {
+		game.Seed = rand.Int63()
+	}
+	game.seedRandom(0)
 	game.Stage = GAME_STARTING
 	// Init arrays
 	game.PlayerMap = make(map[string]*Player)
 	game.Players = make([]*Player, 0)
 	game.Journeys = make([]*WordJourney, 0)
-	game.GameProgressChecker = make(chan struct{}, 10)
-	go game.run()
-	// Start broadcast of player names until game begins
-	game.broadcastPlayers()
+	game.setUpChannels()
+	game.startRunning()
 	return &game
 }
 
-func (g *Game) broadcastPlayers() {
-	ticker := time.NewTicker(1 * time.Second)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				if g.Stage != GAME_STARTING {
-					ticker.Stop()
-					return
-				}
-				g.sendPlayers()
-			}
-		}
-	}()
+// Start the game's random numbers from its seed, skipping the first draws numbers as they've already been used.
+func (g *Game) seedRandom(draws int64) {
+	g.rngSource = &countingSource{source: rand.NewSource(g.Seed).(rand.Source64)}
+	g.rngSource.skip(draws)
+	g.rng = rand.New(g.rngSource)
 }
 
-func (g *Game) StartGame() {
-	if g.Stage == GAME_RUNNING {
-		log.Debug("Attempted to start an already running game")
-		return
+// How many random numbers the game has used since it was seeded.
+func (g *Game) randomDraws() int64 {
+	if g.rngSource == nil {
+		return 0
+	}
+	return g.rngSource.draws
+}
+
+// A random source that counts the numbers it gives out, so a new one from the same seed can be moved on to the same
+// place.
+type countingSource struct {
+	source rand.Source64
+	draws  int64
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.source.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.source.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.draws = 0
+	s.source.Seed(seed)
+}
+
+// Throw away numbers until draws have been given out. Int63 and Uint64 both use up one step of the source.
+func (s *countingSource) skip(draws int64) {
+	for s.draws < draws {
+		s.Uint64()
+	}
+}
+
+// Create the channels and hub the game talks through, without starting anything yet.
+func (g *Game) setUpChannels() {
+	g.GameEvents = make(chan *IncomingMessage, 32)
+	g.ReconnectionChannel = make(chan *Player, 10)
+	g.PresenceChannel = make(chan *PresenceUpdate, 10)
+	g.actions = make(chan func())
+	g.done = make(chan struct{})
+	g.connected = make(map[string]bool)
+	g.Hub = newHub(g.GameEvents, g.ReconnectionChannel, g.PresenceChannel, g.done)
+}
+
+func (g *Game) startRunning() {
+	// Start websocket server
+	go g.Hub.run()
+	go g.run()
+}
+
+// Returned by exec once the game loop has stopped.
+var errGameStopped = errors.New("game has finished")
+
+// Run f inside the game loop and wait for it to finish. Games that aren't running (like ones loaded from the
+// archive) have nothing else touching them, so f is just run straight away. Once the loop has stopped f isn't run at
+// all, whatever stopped it may still be tidying up.
+func (g *Game) exec(f func()) error {
+	if g.actions == nil {
+		f()
+		return nil
 	}
-	// Players can not change at this point. Stops broadcasts.
-	g.Stage = GAME_RUNNING
-	// One final broadcast to make sure we didn't miss any in the last second...
+	finished := make(chan struct{})
+	select {
+	case g.actions <- func() { f(); close(finished) }:
+		<-finished
+		return nil
+	case <-g.done:
+		return errGameStopped
+	}
+}
+
+func (g *Game) StartGame() error {
+	if g.Stage == GAME_STARTING && len(g.Players) < MinPlayers {
+		return newProtocolError(ErrCodeNotEnoughPlayers, "at least %v players are needed", MinPlayers)
+	}
+	next := GAME_RUNNING
+	if g.Settings.CustomPrompts {
+		next = GAME_PROMPTING
+	}
+	err := g.transition(next)
+	if err != nil {
+		return err
+	}
+	g.StartedAt = time.Now()
+	// One final broadcast of the players, who can not change at this point.
 	g.sendPlayers()
 	// Remove join code from map
-	RemoveGameJoinCode(g)
+	if g.store != nil {
+		g.store.RemoveGameJoinCode(g)
+	}
 	// Reset finished players
 	g.PlayersFinished = make([]*Player, 0)
-	// Number of rounds is just number of players
-	g.Limit = len(g.Players)
+	g.Limit = calculateLimit(len(g.Players), &g.Settings)
 	g.Round = 0
-	// Create starting words and distribute them.
+	if g.Stage == GAME_PROMPTING {
+		g.beginPrompting()
+		return nil
+	}
+	g.beginPlay()
+	return nil
+}
+
+// Create starting words and distribute them.
+func (g *Game) beginPlay() {
+	if g.Stage == GAME_PROMPTING {
+		g.transition(GAME_RUNNING)
+	}
 	g.startJourneys()
-	g.sendNextRoundToPlayers()
+	g.beginRound()
 }
 
-func (g *Game) NewPlayer() *Player {
-	name := fmt.Sprintf("Player %v", len(g.Players))
-	playerID, err := uuid.NewRandom()
+// Add a new player to the lobby. Returns a copy of them, as the game is free to change the original.
+func (g *Game) NewPlayer() (*Player, error) {
+	var newPlayer *Player
+	var err error
+	stopped := g.exec(func() {
+		if g.Stage != GAME_STARTING {
+			err = errors.New("game has already started")
+			return
+		}
+		if g.IsLocked() {
+			err = errors.New("game is locked")
+			return
+		}
+		if len(g.Players) >= g.Settings.MaxPlayers {
+			err = errors.New("game is full")
+			return
+		}
+		name := fmt.Sprintf("Player %v", len(g.Players))
+		playerID, uuidErr := uuid.NewRandom()
+		if uuidErr != nil {
+			log.WithError(uuidErr).Fatal("error creating UUID for NewPlayer")
+		}
+		newPlayer = &Player{
+			ID:   playerID.String(),
+			Name: name,
+		}
+		g.Players = append(g.Players, newPlayer)
+		g.PlayerMap[newPlayer.ID] = newPlayer
+		if g.HostID == "" {
+			g.HostID = newPlayer.ID
+		}
+	})
+	if stopped != nil {
+		return nil, stopped
+	}
 	if err != nil {
-		log.WithError(err).Fatal("error creating UUID for NewPlayer")
+		return nil, err
+	}
+	playerCopy := *newPlayer
+	return &playerCopy, nil
+}
+
+// The code the game can be joined (or spectated) with right now, which changes on a rematch.
+func (g *Game) CurrentJoinCode() string {
+	var joinCode string
+	g.exec(func() {
+		joinCode = g.JoinCode
+	})
+	return joinCode
+}
+
+// Find the player with this ID, nil if they aren't in the game or it has finished. The player's ID is safe to read,
+// the rest belongs to the game loop.
+func (g *Game) FindPlayer(playerID string) *Player {
+	var player *Player
+	g.exec(func() {
+		player = g.PlayerMap[playerID]
+	})
+	return player
+}
+
+// The whole game as JSON, for reviewing once it's over.
+func (g *Game) ReviewJSON() ([]byte, error) {
+	var data []byte
+	var err error
+	if stopped := g.exec(func() { data, err = json.Marshal(g) }); stopped != nil {
+		return nil, stopped
 	}
-	newPlayer := &Player{
-		ID:   playerID.String(),
-		Name: name,
+	return data, err
+}
+
+// The players and their points as JSON.
+func (g *Game) ResultsJSON() ([]byte, error) {
+	var data []byte
+	var err error
+	if stopped := g.exec(func() { data, err = json.Marshal(g.Players) }); stopped != nil {
+		return nil, stopped
 	}
-	g.Players = append(g.Players, newPlayer)
-	g.PlayerMap[newPlayer.ID] = newPlayer
-	return newPlayer
+	return data, err
 }
 
+// The game loop. Messages, reconnections, timers and requests from other goroutines are all handled here, one at a
+// time, so nothing else needs to worry about locking.
 func (g *Game) run() {
+	defer close(g.done)
 	timeout := time.After(3 * time.Hour)
+	snapshots := time.NewTicker(snapshotInterval)
+	defer snapshots.Stop()
+	// Broadcast player names every second until the game begins, then the countdown for each round.
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	g.resumeRoundTimer()
 	running := true
 	for running {
 		select {
+		case <-snapshots.C:
+			if g.Stage != GAME_ENDED {
+				g.saveSnapshot()
+			}
+		case <-ticker.C:
+			switch g.Stage {
+			case GAME_STARTING:
+				g.sendPlayers()
+			case GAME_PROMPTING, GAME_RUNNING:
+				g.sendCountdown()
+			}
+		case <-g.roundTimeout():
+			g.roundTimedOut()
+		case <-g.liveStrokesDue():
+			g.sendLiveStrokes()
 		case incomingMessage := <-g.GameEvents:
-			go g.HandleMessage(incomingMessage)
+			g.HandleMessage(incomingMessage)
 		case reconnectingPlayer := <-g.ReconnectionChannel:
 			g.reconnectPlayer(reconnectingPlayer)
-		case <-g.GameProgressChecker:
-			g.checkAndAdvanceRound()
+		case update := <-g.PresenceChannel:
+			g.updatePresence(update)
+		case <-g.hostTimeout():
+			g.hostTimer = nil
+			g.migrateHost()
+		case action := <-g.actions:
+			action()
 		case <-timeout:
-			close(g.ReconnectionChannel)
-			close(g.GameProgressChecker)
-			close(g.GameEvents)
-			UnregisterGame(g.ID)
+			if g.store != nil {
+				g.store.UnregisterGame(g.ID)
+			}
+			g.deleteSnapshot()
+			g.stopRoundTimer()
+			g.stopHostTimer()
+			g.stopLiveStrokes()
 			log.WithField("gameID", g.ID).Debug("game closing")
 			running = false
 		}
 	}
 }
 
-func (g *Game) checkAndAdvanceRound() {
-	// The review process has been finished (that is handled by incrementing the round then giving to sendNextRound)
-	if g.Round == g.Limit {
-		if len(g.PlayersFinished) == len(g.Players) {
-			g.sendResults()
-			g.Stage = GAME_ENDED
+// Move the game on to the next stage, if that's somewhere it can go from here.
+func (g *Game) transition(to GameStage) error {
+	for _, allowed := range stageTransitions[g.Stage] {
+		if allowed == to {
+			log.WithFields(log.Fields{"gameID": g.ID, "from": g.Stage, "to": to}).Debug("game stage changed")
+			g.Stage = to
+			return nil
 		}
-		return
 	}
-	waitingFor := make([]*Player, 0)
-	for _, journey := range g.Journeys {
-		if len(journey.Plays)-1 <= g.Round {
-			waitingFor = append(waitingFor, journey.Order[g.Round])
+	return newProtocolError(ErrCodeWrongStage, "game can not go from %v to %v", g.Stage, to)
+}
+
+func (g *Game) checkAndAdvanceRound() {
+	switch g.Stage {
+	case GAME_RUNNING:
+		if len(g.waitingFor()) > 0 {
+			g.sendProgress()
+			return
 		}
-	}
-	if len(waitingFor) == 0 {
+		// Strokes still waiting to be relayed belong to the round that's ending.
+		g.sendLiveStrokes()
 		g.Round++
-		g.sendNextRoundToPlayers()
+		if g.Round == g.Limit {
+			g.transition(GAME_REVIEWING)
+		}
+		g.beginRound()
+	case GAME_REVIEWING:
+		if len(g.PlayersFinished) == len(g.Players) {
+			g.transition(GAME_ENDED)
+			g.sendResults()
+			g.archiveGame()
+			g.deleteSnapshot()
+		}
 	}
 }
 
-func (g *Game) HandleMessage(message *IncomingMessage) {
-	// Try to deserialize message from JSON as above type.
-	var msg IncomingMessageContents
-	err := json.Unmarshal(message.Message, &msg)
-	if err != nil {
-		log.WithError(err).Error("Could not unmarshal client WebSocket message")
+func (g *Game) archiveGame() {
+	if g.archive == nil {
 		return
 	}
-	if msg.Type == "name" {
-		// Data should be just a string with the new name.
-		newName, ok := msg.Contents.(string)
-		if !ok {
-			log.WithError(err).Error("Could not cast name message contents to string")
-			return
-		}
-		newName = strings.TrimSpace(newName)
-		err = message.Player.SetName(newName)
-		if err != nil {
-			log.WithError(err).Error("Error setting a player's name")
-			return
-		}
-		log.WithFields(log.Fields{"playerID": message.Player, "newName": newName}).Debug("player changed name")
-	}
-	if msg.Type == "start" {
-		// Check correct player started the game for *essential security*.
-		if message.Player != g.Players[0] {
-			log.Error("Incorrect player tried to start the game")
-			return
-		}
-		g.StartGame()
+	err := g.archive.SaveGame(g)
+	if err != nil {
+		log.WithError(err).WithField("gameID", g.ID).Error("could not archive finished game")
+		return
 	}
-	if msg.Type == "drawing" {
-		// Find what journey this belongs to based on the round and player order.
-		// TODO: Make better!
-		for _, journey := range g.Journeys {
-			if journey.Order[g.Round].ID == message.Player.ID {
-				// Right journey!
-				drawData, ok := msg.Contents.(string)
-				if !ok {
-					log.Error("could not cast drawing data to string")
-					return
-				}
-				journey.Plays = append(journey.Plays, &Drawing{
-					Drawing: drawData,
-					Player:  message.Player,
-				})
-				g.GameProgressChecker <- struct{}{}
+	log.WithField("gameID", g.ID).Debug("game archived")
+}
+
+// Players who haven't yet done what they need to for the current round, or written their prompt.
+func (g *Game) waitingFor() []*Player {
+	waitingFor := make([]*Player, 0)
+	switch g.Stage {
+	case GAME_PROMPTING:
+		for _, player := range g.Players {
+			if _, written := g.Prompts[player.ID]; !written {
+				waitingFor = append(waitingFor, player)
 			}
 		}
-	}
-	if msg.Type == "guess" {
-		// Find what journey this belongs to based on the round and player order.
-		// TODO: Make better!
+	case GAME_RUNNING:
 		for _, journey := range g.Journeys {
-			if journey.Order[g.Round].ID == message.Player.ID {
-				// Right journey!
-				guess, ok := msg.Contents.(string)
-				if !ok {
-					log.Error("could not cast guess data to string")
-					return
-				}
-				guess = strings.TrimSpace(guess)
-				journey.Plays = append(journey.Plays, &Word{
-					Word:   guess,
-					Player: message.Player,
-				})
-				g.GameProgressChecker <- struct{}{}
+			if len(journey.Plays)-1 <= g.Round {
+				waitingFor = append(waitingFor, journey.Order[g.Round])
 			}
 		}
 	}
-	if msg.Type == "award" {
-		// TODO: Stop people awarding the same person multiple times per game
-		target, ok := msg.Contents.(string)
-		if !ok {
-			log.WithError(err).Error("Could not cast award message contents to string")
-			return
-		}
-		if target == message.Player.ID {
-			// Nice tryyyyyy
-			return
+	return waitingFor
+}
+
+func (g *Game) isWaitingFor(player *Player) bool {
+	for _, waiting := range g.waitingFor() {
+		if waiting.ID == player.ID {
+			return true
 		}
-		g.PlayerMap[target].Points++
 	}
-	if msg.Type == "done" {
-		for _, player := range g.PlayersFinished {
-			if player.ID == message.Player.ID {
-				// Already registered as done
-				return
-			}
+	return false
+}
+
+// What the players should be sending back this round.
+func (g *Game) expectedPlay() PlayKind {
+	return playKindForRound(g.Round)
+}
+
+// The journey this player is playing on in the current round.
+func (g *Game) journeyForPlayer(player *Player) *WordJourney {
+	for _, journey := range g.Journeys {
+		if journey.Order[g.Round].ID == player.ID {
+			return journey
 		}
-		g.PlayersFinished = append(g.PlayersFinished, message.Player)
-		g.GameProgressChecker <- struct{}{}
 	}
+	return nil
 }
 
 func (g *Game) startJourneys() {
 	// Reset things in case it's a new round.
 	g.Journeys = make([]*WordJourney, 0)
-	for offset, player := range g.Players {
-		word := generateWord(player, g.Players)
-		order := g.calculatePlayOrder(offset)
-		startingPlay := &Word{
-			Word:   word,
-			Player: nil,
-		}
+	if !g.Settings.UniquePrompts {
+		g.WordBag = nil
+	} else if g.WordBag == nil || !g.Settings.UniqueAcrossRematches {
+		g.WordBag = newWordBag(g.rng.Int63())
+	}
+	for _, order := range g.calculatePlayOrders() {
 		newJourney := &WordJourney{
 			Order: order,
 			Plays: make([]GamePlay, 0),
 		}
-		newJourney.Plays = append(newJourney.Plays, startingPlay)
 		g.Journeys = append(g.Journeys, newJourney)
 	}
+	authors := g.promptAuthors()
+	for i, journey := range g.Journeys {
+		var startingPlay *Word
+		if author := authors[i]; author != nil {
+			startingPlay = &Word{Word: g.Prompts[author.ID], Player: author}
+		} else {
+			startingPlay = &Word{Word: generateWord(g, journey.Order[0]), Player: nil}
+		}
+		journey.Plays = append(journey.Plays, startingPlay)
+	}
 }
 
-func (g *Game) calculatePlayOrder(offset int) []*Player {
-	order := make([]*Player, 0)
-	for i, _ := range g.Players {
-		order = append(order, g.Players[(i+offset)%len(g.Players)])
+// The order players play each journey in, using the strategy from the game's settings.
+func (g *Game) calculatePlayOrders() [][]*Player {
+	strategy, found := playOrderStrategies[g.Settings.PlayOrder]
+	if !found {
+		strategy = rotationPlayOrder
 	}
-	return order
+	return strategy(g.Players, g.Limit, g.rng)
 }
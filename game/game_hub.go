@@ -13,16 +13,25 @@ type GameHub struct {
 	incomingMessages chan *IncomingMessage
 	// Reconnecting players
 	reconnections chan *Player
+	// Players connecting and disconnecting
+	presence chan *PresenceUpdate
 	// Messages to send to all clients.
 	broadcasts chan *GameMessage
 	// Messages to send to specific players.
 	messages chan *GameMessage
+	// Live strokes for specific players, which are only worth sending straight away, so they're dropped rather than
+	// held up behind (or holding up) anything else.
+	liveMessages chan *GameMessage
 	// Register requests from the clients.
 	register chan *Client
 	// Unregister requests from clients.
 	unregister chan *Client
+	// Players to disconnect for good, with the last message they'll get.
+	kicks chan *GameMessage
 	// Clients that have been connected before
 	history []string
+	// Closed once the game loop has stopped, so there's nobody left to tell about presence or reconnections.
+	gameDone <-chan struct{}
 }
 
 type GameMessage struct {
@@ -30,26 +39,44 @@ type GameMessage struct {
 	Message *[]byte
 }
 
+// A player's connection coming or going.
+type PresenceUpdate struct {
+	Player    *Player
+	Connected bool
+}
+
 type IncomingMessage struct {
-	Player  *Player
+	Player *Player
+	// Whether the player is only spectating.
+	Spectator bool
+	// Protocol version the player's connection is using.
+	Version int
 	Message []byte
+	// Read from Message by decode, before it reaches the game.
+	decoded     bool
+	envelope    clientEnvelope
+	envelopeErr error
+	msg         clientMessage
+	decodeErr   error
 }
 
-type IncomingMessageContents struct {
-	Type     string      `json:"type"`
-	Contents interface{} `json:"data"`
-}
+// Enough for a round's messages and acks to a full game with a full set of spectators, with room to spare.
+const messageBufferSize = 4 * (maxMaxPlayers + maxSpectators)
 
-func newHub(messageChannel chan *IncomingMessage, reconnectionChannel chan *Player) *GameHub {
+func newHub(messageChannel chan *IncomingMessage, reconnectionChannel chan *Player, presenceChannel chan *PresenceUpdate, gameDone <-chan struct{}) *GameHub {
 	return &GameHub{
 		incomingMessages: messageChannel,
 		reconnections:    reconnectionChannel,
+		presence:         presenceChannel,
 		broadcasts:       make(chan *GameMessage, 32),
-		messages:         make(chan *GameMessage, 64),
+		messages:         make(chan *GameMessage, messageBufferSize),
+		liveMessages:     make(chan *GameMessage, messageBufferSize),
 		register:         make(chan *Client, 10),
 		unregister:       make(chan *Client, 10),
+		kicks:            make(chan *GameMessage, 10),
 		clients:          make(map[string]*Client),
 		history:          make([]string, 0),
+		gameDone:         gameDone,
 	}
 }
 
@@ -59,33 +86,42 @@ func (h *GameHub) run() {
 	for running {
 		select {
 		case client := <-h.register:
+			if oldClient, found := h.clients[client.player.ID]; found {
+				// Same player in a new tab, or reconnecting before the old connection noticed it had gone.
+				close(oldClient.send)
+			}
 			h.clients[client.player.ID] = client
+			h.reportPresence(&PresenceUpdate{Player: client.player, Connected: true})
 			var previousClient = false
 			for _, oldClientID := range h.history {
 				if oldClientID == client.player.ID {
 					// They must be reconnecting, wb!
-					log.WithField("player", client.player).Debug("player reconnected")
+					log.WithField("playerID", client.player.ID).Debug("player reconnected")
 					// Lets give them their last update again in case they missed it.
 					previousClient = true
-					h.reconnections <- client.player
+					select {
+					case h.reconnections <- client.player:
+					case <-h.gameDone:
+					}
 				}
 			}
 			if !previousClient {
 				h.history = append(h.history, client.player.ID)
 			}
 		case client := <-h.unregister:
-			if _, ok := h.clients[client.player.ID]; ok {
-				delete(h.clients, client.player.ID)
-				close(client.send)
+			// Both pumps unregister, and the player may have connected again since, so check it's still this client.
+			if h.clients[client.player.ID] == client {
+				h.disconnect(client)
 			}
+		case message := <-h.kicks:
+			h.kick(message)
 		case message := <-h.broadcasts:
 			for _, client := range h.clients {
 				select {
 				case client.send <- message:
 				default:
 					log.Debug("could not send broadcast")
-					close(client.send)
-					delete(h.clients, client.player.ID)
+					h.disconnect(client)
 				}
 			}
 		case message := <-h.messages:
@@ -104,8 +140,14 @@ func (h *GameHub) run() {
 			default:
 				log.Printf("could not send a message to a player")
 				h.putMessageBack(message)
-				close(client.send)
-				delete(h.clients, client.player.ID)
+				h.disconnect(client)
+			}
+		case message := <-h.liveMessages:
+			if client, found := h.clients[message.Target.ID]; found {
+				select {
+				case client.send <- message:
+				default:
+				}
 			}
 		case <-timeout:
 			for _, client := range h.clients {
@@ -116,6 +158,40 @@ func (h *GameHub) run() {
 	}
 }
 
+// Say goodbye to a player and close their connection. They're forgotten, so they won't count as reconnecting.
+func (h *GameHub) kick(message *GameMessage) {
+	for i, oldClientID := range h.history {
+		if oldClientID == message.Target.ID {
+			h.history = append(h.history[:i], h.history[i+1:]...)
+			break
+		}
+	}
+	client, found := h.clients[message.Target.ID]
+	if !found {
+		return
+	}
+	select {
+	case client.send <- message:
+	default:
+	}
+	h.disconnect(client)
+}
+
+func (h *GameHub) disconnect(client *Client) {
+	close(client.send)
+	delete(h.clients, client.player.ID)
+	h.reportPresence(&PresenceUpdate{Player: client.player, Connected: false})
+}
+
+// Let the game know who's connected. The game loop may have stopped, in which case nobody's listening and waiting would
+// wedge the hub.
+func (h *GameHub) reportPresence(update *PresenceUpdate) {
+	select {
+	case h.presence <- update:
+	case <-h.gameDone:
+	}
+}
+
 // Put messages back on the send queue after an interval
 func (h *GameHub) putMessageBack(message *GameMessage) {
 	// If *everyone* is disconnected, may give up.
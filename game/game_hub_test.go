@@ -0,0 +1,47 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlayersAreToldWhenTheGameCantTakeTheirMessage(t *testing.T) {
+	// Nobody is reading the game's messages, like when it's snowed under.
+	hub := newHub(make(chan *IncomingMessage), make(chan *Player), make(chan *PresenceUpdate), make(chan struct{}))
+	player := &Player{ID: "player-0"}
+	client := &Client{hub: hub, player: player, version: ProtocolVersion}
+	client.forward(testClientMessage(t, player, "stroke-1", "stroke", StrokeMessage{}).Message)
+
+	sent := unwrapMessage(t, <-hub.messages)
+	var refusal ErrorMessage
+	sent.decode(t, &refusal)
+	if sent.target != player || sent.msgType != "error" || refusal.Code != ErrCodeBusy || refusal.RequestID != "stroke-1" {
+		t.Errorf("player was sent %v %s", sent.msgType, sent.data)
+	}
+}
+
+func TestHubKeepsGoingOnceTheGameHasStopped(t *testing.T) {
+	gameDone := make(chan struct{})
+	close(gameDone)
+	// Nobody is reading these, like when the game loop has finished.
+	hub := newHub(make(chan *IncomingMessage), make(chan *Player), make(chan *PresenceUpdate), gameDone)
+	player := &Player{ID: "player-0"}
+	hub.history = append(hub.history, player.ID)
+	go hub.run()
+
+	client := &Client{hub: hub, player: player, send: make(chan *GameMessage, 1)}
+	hub.register <- client
+	// Broadcasts can be picked up before the register, so keep trying until one gets through.
+	message := []byte("hello")
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case hub.broadcasts <- &GameMessage{Message: &message}:
+		case <-client.send:
+			return
+		case <-timeout:
+			t.Fatal("hub got stuck telling a stopped game about the connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,390 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// A game with players, set up but not running, so tests can poke at it directly.
+func newTestGame(t *testing.T, settings GameSettings, players int) *Game {
+	t.Helper()
+	g := &Game{
+		ID:        fmt.Sprintf("test-%v", t.Name()),
+		Stage:     GAME_STARTING,
+		Settings:  settings,
+		Seed:      1,
+		PlayerMap: make(map[string]*Player),
+		blobs:     NewMemoryBlobStore(),
+	}
+	g.seedRandom(0)
+	g.setUpChannels()
+	for i := 0; i < players; i++ {
+		player := &Player{ID: fmt.Sprintf("player-%v", i), Name: fmt.Sprintf("Player %v", i)}
+		g.Players = append(g.Players, player)
+		g.PlayerMap[player.ID] = player
+	}
+	if players > 0 {
+		g.HostID = g.Players[0].ID
+	}
+	return g
+}
+
+// A message from a player, decoded as if it had come in over their connection.
+func testClientMessage(t *testing.T, player *Player, requestID string, msgType string, data interface{}) *IncomingMessage {
+	t.Helper()
+	message, err := json.Marshal(map[string]interface{}{"v": ProtocolVersion, "id": requestID, "type": msgType, "data": data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	incoming := &IncomingMessage{Player: player, Version: ProtocolVersion, Message: message}
+	incoming.decode()
+	return incoming
+}
+
+// A short line down the canvas, moved along by i so each one is different.
+func testStroke(i int) Stroke {
+	return Stroke{Colour: "#000", Width: 3, Points: []StrokePoint{{i, 0, i * 10}, {i, 10, i*10 + 5}}}
+}
+
+// A message the game has sent, out of its envelope.
+type sentMessage struct {
+	target  *Player
+	msgType string
+	data    json.RawMessage
+}
+
+func (m sentMessage) decode(t *testing.T, data interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(m.data, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func unwrapMessage(t *testing.T, message *GameMessage) sentMessage {
+	t.Helper()
+	var envelope struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(*message.Message, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	return sentMessage{target: message.Target, msgType: envelope.Type, data: envelope.Data}
+}
+
+// Take everything the game has sent to single players so far. Only call it in the game loop, or when it isn't
+// running.
+func takeSent(t *testing.T, g *Game) []sentMessage {
+	t.Helper()
+	sent := make([]sentMessage, 0)
+	for _, messages := range []chan *GameMessage{g.Hub.messages, g.Hub.liveMessages} {
+		for len(messages) > 0 {
+			sent = append(sent, unwrapMessage(t, <-messages))
+		}
+	}
+	return sent
+}
+
+// A player connected to a running game through its hub, the way a websocket would be, without the websocket.
+type testClient struct {
+	t          *testing.T
+	g          *Game
+	player     *Player
+	spectating bool
+	client     *Client
+}
+
+// A test game with its hub and loop running, and all its players connected.
+func runTestGame(t *testing.T, settings GameSettings, players int) (*Game, []*testClient) {
+	t.Helper()
+	g := newTestGame(t, settings, players)
+	go g.Hub.run()
+	go g.run()
+	clients := make([]*testClient, players)
+	for i, player := range g.Players {
+		clients[i] = &testClient{t: t, g: g, player: player}
+		clients[i].connect()
+	}
+	return g, clients
+}
+
+// Join a new player to the game and connect them.
+func joinTestClient(t *testing.T, g *Game) *testClient {
+	t.Helper()
+	joined, err := g.NewPlayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{t: t, g: g, player: g.FindPlayer(joined.ID)}
+	c.connect()
+	return c
+}
+
+// Add a spectator to the game and connect them.
+func spectateTestClient(t *testing.T, g *Game) *testClient {
+	t.Helper()
+	joined, err := g.NewSpectator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{t: t, g: g, player: g.FindSpectator(joined.ID), spectating: true}
+	c.connect()
+	return c
+}
+
+// Connect the player, and wait until the game knows they're there. The hub tells the game once it has registered
+// them, so nothing sent to them after this goes missing.
+func (c *testClient) connect() {
+	c.client = &Client{hub: c.g.Hub, player: c.player, version: ProtocolVersion, spectating: c.spectating, send: make(chan *GameMessage, 256)}
+	c.g.Hub.register <- c.client
+	c.waitUntilConnected(true)
+}
+
+func (c *testClient) disconnect() {
+	c.g.Hub.unregister <- c.client
+	c.waitUntilConnected(false)
+}
+
+func (c *testClient) waitUntilConnected(connected bool) {
+	c.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var isConnected bool
+		inspectGame(c.g, func() { isConnected = c.g.connected[c.player.ID] })
+		if isConnected == connected {
+			return
+		}
+		if time.Now().After(deadline) {
+			c.t.Fatalf("game never saw %v's connection change to %v", c.player.ID, connected)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *testClient) send(requestID string, msgType string, data interface{}) {
+	message := testClientMessage(c.t, c.player, requestID, msgType, data)
+	message.Spectator = c.spectating
+	c.g.Hub.incomingMessages <- message
+}
+
+// Wait for the next message of this type, skipping any others, and decode it into data if it isn't nil.
+func (c *testClient) expect(msgType string, data interface{}) {
+	c.t.Helper()
+	c.receive(msgType, data)
+}
+
+// Wait for the next error, and check it has this code.
+func (c *testClient) expectError(code string) {
+	c.t.Helper()
+	var message ErrorMessage
+	c.receive("error", &message)
+	if message.Code != code {
+		c.t.Errorf("%v was sent a %v error, want %v: %v", c.player.ID, message.Code, code, message.Message)
+	}
+}
+
+// Wait for the hub to close the player's connection.
+func (c *testClient) expectDisconnected() {
+	c.t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, open := <-c.client.send:
+			if !open {
+				return
+			}
+		case <-deadline:
+			c.t.Fatalf("%v was never disconnected", c.player.ID)
+		}
+	}
+}
+
+func (c *testClient) receive(msgType string, data interface{}) {
+	c.t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		var message *GameMessage
+		var open bool
+		select {
+		case message, open = <-c.client.send:
+			if !open {
+				c.t.Fatalf("%v was disconnected waiting for %v", c.player.ID, msgType)
+			}
+		case <-deadline:
+			c.t.Fatalf("%v never got a %v message", c.player.ID, msgType)
+		}
+		sent := unwrapMessage(c.t, message)
+		if sent.msgType == "error" && msgType != "error" {
+			c.t.Fatalf("%v was sent an error waiting for %v: %s", c.player.ID, msgType, sent.data)
+		}
+		if sent.msgType != msgType {
+			continue
+		}
+		if data != nil {
+			sent.decode(c.t, data)
+		}
+		return
+	}
+}
+
+// Look at the game from inside its loop once it has stopped changing.
+func inspectGame(g *Game, f func()) {
+	g.exec(f)
+}
+
+func TestNothingIsRunOnceTheGameHasStopped(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	close(g.done)
+	ran := false
+	if err := g.exec(func() { ran = true }); err != errGameStopped || ran {
+		t.Errorf("exec gave %v, and ran is %v", err, ran)
+	}
+	if _, err := g.NewPlayer(); err != errGameStopped {
+		t.Errorf("joining a stopped game gave %v", err)
+	}
+}
+
+func TestGamePlayedThrough(t *testing.T) {
+	g := NewGame(nil, NewMemoryBlobStore(), DefaultSettings())
+	clients := make([]*testClient, 3)
+	for i := range clients {
+		clients[i] = joinTestClient(t, g)
+	}
+	for _, c := range clients {
+		c.expect("players", nil)
+	}
+	host := clients[0]
+	host.send("start", "start", nil)
+
+	// Three players, so three rounds: drawing the starting word, guessing the drawing, drawing the guess.
+	for _, c := range clients {
+		var word WordMessage
+		c.expect("word", &word)
+		if word.Round != 0 || word.Word == "" {
+			t.Fatalf("%v started with %+v", c.player.ID, word)
+		}
+		c.send("draw-0", "drawing", DrawingMessage{Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}}})
+	}
+	for _, c := range clients {
+		var drawing DrawingRoundMessage
+		c.expect("drawing", &drawing)
+		if drawing.Round != 1 || len(drawing.Strokes) == 0 {
+			t.Fatalf("%v was given %+v to guess", c.player.ID, drawing)
+		}
+		c.send("guess-1", "guess", GuessMessage{Guess: "guess by " + c.player.ID})
+	}
+	for _, c := range clients {
+		var word WordMessage
+		c.expect("word", &word)
+		if word.Round != 2 || word.Word == "" || word.Word == "guess by "+c.player.ID {
+			t.Fatalf("%v was given %+v to draw", c.player.ID, word)
+		}
+		// The last player draws by streaming strokes in.
+		if c == clients[2] {
+			for i := 0; i < 3; i++ {
+				c.send("", "stroke", StrokeMessage{Width: 100, Height: 100, Stroke: testStroke(i)})
+			}
+			c.send("draw-2", "drawing", DrawingMessage{Streamed: true})
+		} else {
+			c.send("draw-2", "drawing", DrawingMessage{Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}}})
+		}
+	}
+	for _, c := range clients {
+		c.expect("review", nil)
+	}
+	clients[1].send("award", "award", AwardMessage{PlayerID: host.player.ID})
+	for _, c := range clients {
+		c.send("done", "done", nil)
+	}
+	var results ResultsMessage
+	for _, c := range clients {
+		c.expect("results", &results)
+	}
+	if results.Players[0].Points != 1 {
+		t.Errorf("host finished on %v points, want 1", results.Players[0].Points)
+	}
+
+	inspectGame(g, func() {
+		if g.Stage != GAME_ENDED {
+			t.Errorf("game is %v, want it ended", g.Stage)
+		}
+		for i, journey := range g.Journeys {
+			if len(journey.Plays) != 4 {
+				t.Errorf("journey %v has %v plays, want 4", i, len(journey.Plays))
+				continue
+			}
+			streamed := journey.Plays[3].(*Drawing)
+			if streamed.Player.ID == clients[2].player.ID && len(streamed.Timeline.Strokes) != 3 {
+				t.Errorf("streamed drawing has %v strokes", len(streamed.Timeline.Strokes))
+			}
+		}
+	})
+}
+
+func TestRoundsTimeOut(t *testing.T) {
+	settings := DefaultSettings()
+	settings.DrawTime = 1
+	settings.GuessTime = 1
+	settings.Rounds = 2
+	g := NewGame(nil, NewMemoryBlobStore(), settings)
+	clients := []*testClient{joinTestClient(t, g), joinTestClient(t, g)}
+	clients[0].send("start", "start", nil)
+	// Nobody plays anything, the clock moves them on anyway.
+	for _, c := range clients {
+		c.expect("word", nil)
+	}
+	for _, c := range clients {
+		var drawing DrawingRoundMessage
+		c.expect("drawing", &drawing)
+		if drawing.Round != 1 || drawing.Drawing != "" || len(drawing.Strokes) != 0 {
+			t.Errorf("%v was given %+v, want a blank drawing", c.player.ID, drawing)
+		}
+	}
+	for _, c := range clients {
+		c.expect("review", nil)
+	}
+	inspectGame(g, func() {
+		for _, journey := range g.Journeys {
+			if guess := journey.Plays[2].GetPlay(); guess != missingGuess {
+				t.Errorf("guess that ran out of time is %q", guess)
+			}
+		}
+	})
+}
+
+func TestReconnectingPlayersCatchUp(t *testing.T) {
+	g := NewGame(nil, NewMemoryBlobStore(), DefaultSettings())
+	clients := []*testClient{joinTestClient(t, g), joinTestClient(t, g)}
+	clients[0].send("start", "start", nil)
+	var word WordMessage
+	drawer := clients[1]
+	drawer.expect("word", &word)
+	drawer.send("", "stroke", StrokeMessage{Width: 100, Height: 100, Stroke: testStroke(0)})
+	// Strokes aren't acknowledged, but messages are handled in order.
+	drawer.send("name", "name", NameMessage{Name: "Drawer"})
+	drawer.expect("ack", nil)
+
+	drawer.disconnect()
+	drawer.connect()
+	// They get their word again, and what they'd drawn of it so far.
+	var again WordMessage
+	drawer.expect("word", &again)
+	if again != word {
+		t.Errorf("reconnected to %+v, was playing %+v", again, word)
+	}
+	var recording RecordingMessage
+	drawer.expect("recording", &recording)
+	if recording.Round != 0 || len(recording.Drawing.Strokes) != 1 {
+		t.Errorf("reconnected to recording %+v", recording)
+	}
+	drawer.send("draw", "drawing", DrawingMessage{Streamed: true})
+	var progress ProgressMessage
+	for len(progress.WaitingFor) != 1 {
+		drawer.expect("progress", &progress)
+	}
+	if progress.WaitingFor[0] != clients[0].player.ID {
+		t.Errorf("still waiting for %v", progress.WaitingFor)
+	}
+}
@@ -0,0 +1,280 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// Messages clients can send, and what the game does with them.
+
+type clientMessage interface {
+	handle(g *Game, player *Player) error
+}
+
+// Messages with work to do that's too slow for the game loop, like checking images, do it in prepare. It's called
+// before the message is passed to the game, so any errors are kept for handle to report in turn.
+type preparedMessage interface {
+	prepare()
+}
+
+// Change the player's display name.
+type NameMessage struct {
+	Name string `json:"name"`
+}
+
+// Start the game, only the host can do this.
+type StartMessage struct{}
+
+// A finished drawing for the current round, either as an image, the strokes that made it, or the strokes already
+// streamed in with stroke messages.
+type DrawingMessage struct {
+	Drawing  string         `json:"drawing,omitempty"`
+	Strokes  *StrokeDrawing `json:"strokes,omitempty"`
+	Streamed bool           `json:"streamed,omitempty"`
+	// Filled in by prepare: the image checked over and turned into a PNG, and whether the drawing was any good.
+	prepared bool
+	image    []byte
+	err      error
+}
+
+// A guess at the drawing for the current round.
+type GuessMessage struct {
+	Guess string `json:"guess"`
+}
+
+// Give another player a point during the review.
+type AwardMessage struct {
+	PlayerID string `json:"playerID"`
+}
+
+// The player has finished reviewing.
+type DoneMessage struct{}
+
+// Each message type the client can send, and how to make an empty one to decode into.
+var clientMessages = map[string]func() clientMessage{
+	"name":    func() clientMessage { return &NameMessage{} },
+	"start":   func() clientMessage { return &StartMessage{} },
+	"drawing": func() clientMessage { return &DrawingMessage{} },
+	"stroke":  func() clientMessage { return &StrokeMessage{} },
+	"guess":   func() clientMessage { return &GuessMessage{} },
+	"award":   func() clientMessage { return &AwardMessage{} },
+	"done":    func() clientMessage { return &DoneMessage{} },
+	// Host controls
+	"kick":         func() clientMessage { return &KickMessage{} },
+	"transferHost": func() clientMessage { return &TransferHostMessage{} },
+	"lock":         func() clientMessage { return &LockMessage{} },
+	"settings":     func() clientMessage { return &SettingsMessage{} },
+	"rematch":      func() clientMessage { return &RematchMessage{} },
+	"prompt":       func() clientMessage { return &PromptMessage{} },
+}
+
+// Read the message and get it ready to handle. This happens in the connection's goroutine, so the game loop isn't held
+// up by it.
+func (m *IncomingMessage) decode() {
+	m.decoded = true
+	err := json.Unmarshal(m.Message, &m.envelope)
+	if err != nil {
+		m.envelopeErr = newProtocolError(ErrCodeBadMessage, "could not read message: %v", err)
+		return
+	}
+	newMessage, found := clientMessages[m.envelope.Type]
+	if !found {
+		m.decodeErr = newProtocolError(ErrCodeUnknownType, "unknown message type %q", m.envelope.Type)
+		return
+	}
+	msg := newMessage()
+	if len(m.envelope.Data) > 0 && !bytes.Equal(m.envelope.Data, []byte("null")) {
+		err = json.Unmarshal(m.envelope.Data, msg)
+		if err != nil {
+			m.decodeErr = newProtocolError(ErrCodeBadMessage, "could not read %v message: %v", m.envelope.Type, err)
+			return
+		}
+	}
+	if prepared, ok := msg.(preparedMessage); ok {
+		prepared.prepare()
+	}
+	m.msg = msg
+}
+
+// Handle a message from a player, and let them know how it went.
+func (g *Game) HandleMessage(message *IncomingMessage) {
+	if !message.decoded {
+		message.decode()
+	}
+	envelope := &message.envelope
+	err := g.dispatchMessage(message, envelope)
+	if err != nil {
+		protocolErr := asProtocolError(err)
+		log.WithFields(log.Fields{
+			"gameID":    g.ID,
+			"playerID":  message.Player.ID,
+			"code":      protocolErr.Code,
+			"requestID": envelope.ID,
+		}).WithError(err).Debug("could not handle client message")
+		g.sendError(message.Player, envelope.ID, protocolErr)
+		return
+	}
+	if _, isStroke := message.msg.(*StrokeMessage); isStroke {
+		// No news is good news, see protocol.go.
+		return
+	}
+	g.sendAck(message.Player, envelope.ID, envelope.Type)
+}
+
+func (g *Game) dispatchMessage(message *IncomingMessage, envelope *clientEnvelope) error {
+	if message.envelopeErr != nil {
+		return message.envelopeErr
+	}
+	if message.Spectator {
+		return newProtocolError(ErrCodeSpectator, "spectators can't play")
+	}
+	if envelope.Version != 0 && envelope.Version != message.Version {
+		return newProtocolError(ErrCodeUnsupportedVersion, "connection is using protocol version %v", message.Version)
+	}
+	if message.decodeErr != nil {
+		return message.decodeErr
+	}
+	return message.msg.handle(g, message.Player)
+}
+
+func (m *NameMessage) handle(g *Game, player *Player) error {
+	newName := strings.TrimSpace(m.Name)
+	err := player.SetName(newName)
+	if err != nil {
+		return newProtocolError(ErrCodeInvalidName, "%v", err)
+	}
+	log.WithFields(log.Fields{"playerID": player, "newName": newName}).Debug("player changed name")
+	return nil
+}
+
+func (m *StartMessage) handle(g *Game, player *Player) error {
+	// Check correct player started the game for *essential security*.
+	if !g.isHost(player) {
+		return newProtocolError(ErrCodeNotHost, "only the host can start the game")
+	}
+	return g.StartGame()
+}
+
+func (m *DrawingMessage) handle(g *Game, player *Player) error {
+	journey, err := g.journeyToPlay(player, PLAY_DRAWING)
+	if err != nil {
+		return err
+	}
+	if !m.prepared {
+		m.prepare()
+	}
+	drawing := &Drawing{Player: player}
+	switch {
+	case m.Streamed:
+		recording := g.currentRecording(player)
+		if recording == nil {
+			return newProtocolError(ErrCodeInvalidDrawing, "no strokes have been sent for this drawing")
+		}
+		drawing, err = g.storeStrokeDrawing(player, recording.Drawing, recording.Received)
+	case m.err != nil:
+		return newProtocolError(ErrCodeInvalidDrawing, "%v", m.err)
+	case m.Strokes != nil:
+		drawing, err = g.storeStrokeDrawing(player, m.Strokes, nil)
+	case m.Drawing != "":
+		drawing.Format = DRAWING_PNG
+		drawing.Ref = g.putDrawing(DRAWING_PNG, m.image)
+	}
+	if err != nil {
+		log.WithError(err).WithField("gameID", g.ID).Error("could not store drawing")
+		return newProtocolError(ErrCodeInternal, "your drawing couldn't be saved, try again")
+	}
+	delete(g.recordings, player.ID)
+	journey.Plays = append(journey.Plays, drawing)
+	g.checkAndAdvanceRound()
+	return nil
+}
+
+// Check the drawing over, turning images into PNGs.
+func (m *DrawingMessage) prepare() {
+	m.prepared = true
+	switch {
+	case m.Streamed:
+	case m.Strokes != nil:
+		m.err = m.Strokes.Validate()
+	case m.Drawing != "":
+		m.image, m.err = sanitiseImageDrawing(m.Drawing)
+	}
+}
+
+func (m *GuessMessage) handle(g *Game, player *Player) error {
+	journey, err := g.journeyToPlay(player, PLAY_GUESS)
+	if err != nil {
+		return err
+	}
+	journey.Plays = append(journey.Plays, &Word{
+		Word:   strings.TrimSpace(m.Guess),
+		Player: player,
+	})
+	g.checkAndAdvanceRound()
+	return nil
+}
+
+// Find the journey the player should be adding this kind of play to, if they're allowed to right now.
+func (g *Game) journeyToPlay(player *Player, kind PlayKind) (*WordJourney, error) {
+	if g.Stage != GAME_RUNNING {
+		return nil, newProtocolError(ErrCodeWrongStage, "the game isn't running")
+	}
+	if g.expectedPlay() != kind {
+		return nil, newProtocolError(ErrCodeWrongStage, "this round needs a %v, not a %v", g.expectedPlay(), kind)
+	}
+	journey := g.journeyForPlayer(player)
+	if journey == nil {
+		return nil, newProtocolError(ErrCodeNotYourTurn, "you aren't playing this round")
+	}
+	if len(journey.Plays) > g.Round+1 {
+		return nil, newProtocolError(ErrCodeAlreadyPlayed, "you've already played this round")
+	}
+	return journey, nil
+}
+
+func (m *AwardMessage) handle(g *Game, player *Player) error {
+	if g.Stage != GAME_REVIEWING && g.Stage != GAME_ENDED {
+		return newProtocolError(ErrCodeWrongStage, "points can only be awarded after the game")
+	}
+	if g.Settings.ScoringMode == SCORING_NONE {
+		return newProtocolError(ErrCodeNotAllowed, "this game doesn't have points")
+	}
+	if m.PlayerID == player.ID {
+		// Nice tryyyyyy
+		return newProtocolError(ErrCodeNotAllowed, "you can't award yourself")
+	}
+	target, found := g.PlayerMap[m.PlayerID]
+	if !found {
+		return newProtocolError(ErrCodePlayerNotFound, "player not found")
+	}
+	if g.Settings.ScoringMode == SCORING_ONE_AWARD_EACH {
+		if g.Awards == nil {
+			g.Awards = make(map[string][]string)
+		}
+		for _, awarded := range g.Awards[player.ID] {
+			if awarded == target.ID {
+				return newProtocolError(ErrCodeNotAllowed, "you've already awarded %v", target.Name)
+			}
+		}
+		g.Awards[player.ID] = append(g.Awards[player.ID], target.ID)
+	}
+	target.Points++
+	return nil
+}
+
+func (m *DoneMessage) handle(g *Game, player *Player) error {
+	if g.Stage != GAME_REVIEWING {
+		return newProtocolError(ErrCodeWrongStage, "the game isn't being reviewed")
+	}
+	for _, finished := range g.PlayersFinished {
+		if finished.ID == player.ID {
+			// Already registered as done
+			return nil
+		}
+	}
+	g.PlayersFinished = append(g.PlayersFinished, player)
+	g.checkAndAdvanceRound()
+	return nil
+}
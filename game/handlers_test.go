@@ -0,0 +1,98 @@
+package game
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func testImageDataURL(t *testing.T, width int, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return pngDataURL(buf.Bytes())
+}
+
+func TestImageDrawingsAreCheckedBeforeReachingTheGame(t *testing.T) {
+	player := &Player{ID: "player-0"}
+	message := testClientMessage(t, player, "1", "drawing", DrawingMessage{Drawing: testImageDataURL(t, 20, 10)})
+	drawing, ok := message.msg.(*DrawingMessage)
+	if !ok || !drawing.prepared {
+		t.Fatalf("drawing wasn't prepared: %#v", message.msg)
+	}
+	if drawing.err != nil || len(drawing.image) == 0 {
+		t.Errorf("good drawing came out as %v, %v bytes", drawing.err, len(drawing.image))
+	}
+
+	message = testClientMessage(t, player, "2", "drawing", DrawingMessage{Drawing: "data:image/png;base64,bm90IGFuIGltYWdl"})
+	if drawing = message.msg.(*DrawingMessage); drawing.err == nil {
+		t.Error("bad drawing wasn't caught")
+	}
+}
+
+func TestMessagesAreAnswered(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	player := g.Players[1]
+	tests := []struct {
+		name    string
+		message *IncomingMessage
+		// The error code the player should get back, empty for an ack.
+		code string
+	}{
+		{"rename", testClientMessage(t, player, "1", "name", NameMessage{Name: "  Alice  "}), ""},
+		{"blank name", testClientMessage(t, player, "2", "name", NameMessage{Name: " "}), ErrCodeInvalidName},
+		{"start without being host", testClientMessage(t, player, "3", "start", nil), ErrCodeNotHost},
+		{"guess in the lobby", testClientMessage(t, player, "4", "guess", GuessMessage{Guess: "Cat"}), ErrCodeWrongStage},
+		{"unknown type", testClientMessage(t, player, "5", "dance", nil), ErrCodeUnknownType},
+		{"bad data", testClientMessage(t, player, "6", "name", "Alice"), ErrCodeBadMessage},
+		{"not JSON", &IncomingMessage{Player: player, Version: ProtocolVersion, Message: []byte("{nope")}, ErrCodeBadMessage},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g.HandleMessage(test.message)
+			sent := takeSent(t, g)
+			if len(sent) != 1 || sent[0].target != player {
+				t.Fatalf("sent %+v, want one answer to the player", sent)
+			}
+			requestID := test.message.envelope.ID
+			if test.code == "" {
+				var ack AckMessage
+				sent[0].decode(t, &ack)
+				if sent[0].msgType != "ack" || ack.RequestID != requestID || ack.Type != test.message.envelope.Type {
+					t.Errorf("answered with %v %s, want an ack", sent[0].msgType, sent[0].data)
+				}
+				return
+			}
+			var refusal ErrorMessage
+			sent[0].decode(t, &refusal)
+			if sent[0].msgType != "error" || refusal.RequestID != requestID || refusal.Code != test.code {
+				t.Errorf("answered with %v %s, want a %v error", sent[0].msgType, sent[0].data, test.code)
+			}
+		})
+	}
+	if player.Name != "Alice" {
+		t.Errorf("player is called %q", player.Name)
+	}
+}
+
+func TestImageDrawingsArePlayed(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 2)
+	player := clients[0]
+	player.send("start", "start", nil)
+	player.expect("word", nil)
+	player.send("drawing-1", "drawing", DrawingMessage{Drawing: testImageDataURL(t, 20, 10)})
+	var ack AckMessage
+	for ack.RequestID != "drawing-1" {
+		player.expect("ack", &ack)
+	}
+	inspectGame(g, func() {
+		journey := g.journeyForPlayer(player.player)
+		drawing, ok := journey.Plays[len(journey.Plays)-1].(*Drawing)
+		if !ok || drawing.Format != DRAWING_PNG || drawing.Ref == "" {
+			t.Errorf("played %#v", journey.Plays[len(journey.Plays)-1])
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package game
+
+import (
+	log "github.com/sirupsen/logrus"
+	"sync/atomic"
+	"time"
+)
+
+// Remove a player from the lobby, only the host can do this.
+type KickMessage struct {
+	PlayerID string `json:"playerID"`
+}
+
+// Hand the host role to another player.
+type TransferHostMessage struct {
+	PlayerID string `json:"playerID"`
+}
+
+// Stop or allow new players joining the lobby.
+type LockMessage struct {
+	Locked bool `json:"locked"`
+}
+
+// Sent to a player just before they're removed from the game.
+type KickedMessage struct{}
+
+// Someone new is in charge.
+type HostMessage struct {
+	HostID string `json:"hostID"`
+}
+
+// How long the host can be disconnected before someone else takes over.
+const hostMigrationDelay = 10 * time.Second
+
+func (g *Game) isHost(player *Player) bool {
+	return player != nil && player.ID == g.HostID
+}
+
+// Whether the host has stopped new players joining. Safe to call from any goroutine.
+func (g *Game) IsLocked() bool {
+	return atomic.LoadInt32(&g.locked) == 1
+}
+
+func (g *Game) setLocked(locked bool) {
+	var value int32
+	if locked {
+		value = 1
+	}
+	atomic.StoreInt32(&g.locked, value)
+}
+
+func (m *KickMessage) handle(g *Game, player *Player) error {
+	if !g.isHost(player) {
+		return newProtocolError(ErrCodeNotHost, "only the host can kick players")
+	}
+	if g.Stage != GAME_STARTING {
+		return newProtocolError(ErrCodeWrongStage, "players can only be kicked from the lobby")
+	}
+	target, found := g.PlayerMap[m.PlayerID]
+	if !found {
+		return newProtocolError(ErrCodePlayerNotFound, "player not found")
+	}
+	if target == player {
+		return newProtocolError(ErrCodeNotAllowed, "you can't kick yourself")
+	}
+	g.removePlayer(target)
+	kicked, err := encodeMessage("kicked", KickedMessage{})
+	if err != nil {
+		return err
+	}
+	select {
+	case g.Hub.kicks <- &GameMessage{Target: target, Message: &kicked}:
+	default:
+		log.Error("could not dispatch kick")
+	}
+	log.WithFields(log.Fields{"gameID": g.ID, "playerID": target.ID}).Debug("player kicked")
+	g.sendPlayers()
+	return nil
+}
+
+func (g *Game) removePlayer(target *Player) {
+	delete(g.PlayerMap, target.ID)
+	delete(g.connected, target.ID)
+	for i, player := range g.Players {
+		if player == target {
+			g.Players = append(g.Players[:i], g.Players[i+1:]...)
+			break
+		}
+	}
+}
+
+func (m *TransferHostMessage) handle(g *Game, player *Player) error {
+	if !g.isHost(player) {
+		return newProtocolError(ErrCodeNotHost, "only the host can hand over hosting")
+	}
+	target, found := g.PlayerMap[m.PlayerID]
+	if !found {
+		return newProtocolError(ErrCodePlayerNotFound, "player not found")
+	}
+	g.setHost(target)
+	log.WithFields(log.Fields{"gameID": g.ID, "hostID": target.ID}).Debug("host transferred")
+	return nil
+}
+
+func (g *Game) setHost(player *Player) {
+	g.HostID = player.ID
+	g.stopHostTimer()
+	if !g.connected[player.ID] {
+		g.startHostTimer()
+	}
+	g.broadcast("host", HostMessage{HostID: g.HostID})
+	g.sendPlayers()
+}
+
+// Keep track of who is connected, and start looking for a new host if it's the host that has gone.
+func (g *Game) updatePresence(update *PresenceUpdate) {
+	if _, spectating := g.Spectators[update.Player.ID]; spectating {
+		// Spectators are caught up whenever they connect, whether or not it's the first time.
+		if update.Connected {
+			g.connected[update.Player.ID] = true
+			g.catchUpSpectator(update.Player)
+		} else {
+			delete(g.connected, update.Player.ID)
+		}
+		return
+	}
+	if _, found := g.PlayerMap[update.Player.ID]; !found {
+		// Kicked, or not a player at all.
+		return
+	}
+	if update.Connected {
+		g.connected[update.Player.ID] = true
+	} else {
+		delete(g.connected, update.Player.ID)
+	}
+	if g.connected[g.HostID] {
+		g.stopHostTimer()
+	} else if g.hostTimer == nil {
+		g.startHostTimer()
+	}
+}
+
+// Hand hosting to the next connected player after the current host, if the host still hasn't come back.
+func (g *Game) migrateHost() {
+	if g.connected[g.HostID] || g.Stage == GAME_ENDED {
+		return
+	}
+	hostIndex := 0
+	for i, player := range g.Players {
+		if player.ID == g.HostID {
+			hostIndex = i
+			break
+		}
+	}
+	for i := 1; i <= len(g.Players); i++ {
+		candidate := g.Players[(hostIndex+i)%len(g.Players)]
+		if g.connected[candidate.ID] {
+			log.WithFields(log.Fields{"gameID": g.ID, "oldHostID": g.HostID, "hostID": candidate.ID}).Debug("host migrated")
+			g.setHost(candidate)
+			return
+		}
+	}
+	// Nobody is around, updatePresence will try again when someone connects.
+}
+
+func (g *Game) startHostTimer() {
+	g.hostTimer = time.NewTimer(hostMigrationDelay)
+}
+
+func (g *Game) stopHostTimer() {
+	if g.hostTimer != nil {
+		g.hostTimer.Stop()
+		g.hostTimer = nil
+	}
+}
+
+// The channel the host's grace period running out is sent on. Nil (so never ready) when the host is around.
+func (g *Game) hostTimeout() <-chan time.Time {
+	if g.hostTimer == nil {
+		return nil
+	}
+	return g.hostTimer.C
+}
+
+func (m *LockMessage) handle(g *Game, player *Player) error {
+	if !g.isHost(player) {
+		return newProtocolError(ErrCodeNotHost, "only the host can lock the lobby")
+	}
+	if g.Stage != GAME_STARTING {
+		return newProtocolError(ErrCodeWrongStage, "only the lobby can be locked")
+	}
+	g.setLocked(m.Locked)
+	g.sendPlayers()
+	return nil
+}
@@ -0,0 +1,104 @@
+package game
+
+import "testing"
+
+func TestHostLeavingHandsOverToTheNextConnectedPlayer(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 4)
+	clients[0].disconnect()
+	clients[1].disconnect()
+	var waiting bool
+	inspectGame(g, func() { waiting = g.hostTimer != nil })
+	if !waiting {
+		t.Fatal("host left without the clock starting on them")
+	}
+	// Rather than sit out the grace period, do what the game loop does when it runs out.
+	inspectGame(g, func() {
+		g.stopHostTimer()
+		g.migrateHost()
+	})
+	newHostID := clients[2].player.ID
+	for _, c := range clients[2:] {
+		var host HostMessage
+		c.expect("host", &host)
+		if host.HostID != newHostID {
+			t.Errorf("%v was told the host is %v, want %v", c.player.ID, host.HostID, newHostID)
+		}
+		// The lobby keeps sending players out, so skip any from before the change.
+		var players PlayersMessage
+		for players.HostID != newHostID {
+			c.expect("players", &players)
+		}
+	}
+}
+
+func TestHostComingBackKeepsTheRole(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 2)
+	clients[0].disconnect()
+	clients[0].connect()
+	var waiting bool
+	var hostID string
+	inspectGame(g, func() {
+		waiting = g.hostTimer != nil
+		g.migrateHost()
+		hostID = g.HostID
+	})
+	if waiting || hostID != clients[0].player.ID {
+		t.Errorf("host came back but the clock is running %v and the host is %v", waiting, hostID)
+	}
+}
+
+func TestOnlyTheHostRunsTheLobby(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 3)
+	player := clients[1]
+	player.send("kick", "kick", KickMessage{PlayerID: clients[2].player.ID})
+	player.expectError(ErrCodeNotHost)
+	player.send("transfer", "transferHost", TransferHostMessage{PlayerID: player.player.ID})
+	player.expectError(ErrCodeNotHost)
+	player.send("lock", "lock", LockMessage{Locked: true})
+	player.expectError(ErrCodeNotHost)
+	var hostID string
+	var players int
+	inspectGame(g, func() {
+		hostID = g.HostID
+		players = len(g.Players)
+	})
+	if hostID != clients[0].player.ID || players != 3 || g.IsLocked() {
+		t.Errorf("lobby was changed by a player who isn't the host")
+	}
+}
+
+func TestKickedPlayersAreRemovedAndDisconnected(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 3)
+	host, kicked := clients[0], clients[2]
+	host.send("kick", "kick", KickMessage{PlayerID: kicked.player.ID})
+	host.expect("ack", nil)
+	kicked.expect("kicked", nil)
+	kicked.expectDisconnected()
+	var players PlayersMessage
+	for len(players.Players) != 2 {
+		host.expect("players", &players)
+	}
+	for _, player := range players.Players {
+		if player.ID == kicked.player.ID {
+			t.Errorf("%v is still in the lobby", player.ID)
+		}
+	}
+	if found := g.FindPlayer(kicked.player.ID); found != nil {
+		t.Errorf("%v can still be found", found.ID)
+	}
+}
+
+func TestLockedLobbiesCantBeJoined(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 2)
+	host := clients[0]
+	host.send("lock", "lock", LockMessage{Locked: true})
+	host.expect("ack", nil)
+	if _, err := g.NewPlayer(); err == nil {
+		t.Error("joined a locked lobby")
+	}
+	host.send("unlock", "lock", LockMessage{Locked: false})
+	host.expect("ack", nil)
+	if _, err := g.NewPlayer(); err != nil {
+		t.Errorf("couldn't join an unlocked lobby: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package game
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+
+	// Decoders for the other image types clients may send.
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// Drawings sent as images have to be a base64 data URL of one of these, and get turned into a PNG whatever they were,
+// so nothing but a plain image ever reaches the other players.
+var allowedImageTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+}
+
+// After base64 decoding, which is about as big as fits in a message.
+const maxImageBytes = 192 * 1024
+
+var errNotDataURL = errors.New("drawing must be a base64 data URL")
+
+// Check the drawing is an image we're happy with, and return it re-encoded as a PNG.
+func sanitiseImageDrawing(dataURL string) ([]byte, error) {
+	if !strings.HasPrefix(dataURL, "data:") {
+		return nil, errNotDataURL
+	}
+	comma := strings.IndexByte(dataURL, ',')
+	if comma < 0 {
+		return nil, errNotDataURL
+	}
+	header := dataURL[len("data:"):comma]
+	if !strings.HasSuffix(header, ";base64") {
+		return nil, errNotDataURL
+	}
+	mimeType := strings.ToLower(strings.TrimSuffix(header, ";base64"))
+	expectedFormat, allowed := allowedImageTypes[mimeType]
+	if !allowed {
+		return nil, fmt.Errorf("drawing can't be a %q", mimeType)
+	}
+	encoded := dataURL[comma+1:]
+	if base64.StdEncoding.DecodedLen(len(encoded)) > maxImageBytes {
+		return nil, fmt.Errorf("drawing can be at most %vKB", maxImageBytes/1024)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errNotDataURL
+	}
+	// Check the size before decoding the whole thing, a tiny file can claim to be enormous.
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("drawing isn't a readable image")
+	}
+	if format != expectedFormat {
+		return nil, fmt.Errorf("drawing says it's a %q but is really a %v", mimeType, format)
+	}
+	if config.Width < 1 || config.Width > maxCanvasSize || config.Height < 1 || config.Height > maxCanvasSize {
+		return nil, fmt.Errorf("drawing must be between 1 and %v pixels each way", maxCanvasSize)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("drawing isn't a readable image")
+	}
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Turn a stored PNG back into a data URL for sending to a player.
+func pngDataURL(data []byte) string {
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,139 @@
+package game
+
+import (
+	"time"
+)
+
+// With LiveDrawings on, strokes streamed in during a drawing round are passed on to spectators and to players who have
+// already finished, so they've got something to watch. They're batched up and sent at most this often, and never to
+// anyone who has still to play on that drawing's journey.
+const liveStrokeInterval = 250 * time.Millisecond
+
+// A batch of strokes from drawings in progress.
+type LiveStrokesMessage struct {
+	Round   int          `json:"round"`
+	Strokes []LiveStroke `json:"strokes"`
+}
+
+type LiveStroke struct {
+	PlayerID string `json:"playerID"`
+	// Which stroke of the player's drawing this is, so gaps can be noticed.
+	Index  int    `json:"index"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Stroke Stroke `json:"stroke"`
+	// IDs of who mustn't see it.
+	hiddenFrom map[string]bool
+}
+
+// Queue up the latest stroke of the player's drawing to be passed on.
+func (g *Game) relayStroke(player *Player, recording *strokeRecording) {
+	if !g.Settings.LiveDrawings {
+		return
+	}
+	g.liveStrokes = append(g.liveStrokes, g.liveStroke(player, recording, len(recording.Drawing.Strokes)-1))
+	if g.liveTimer == nil {
+		g.liveTimer = time.NewTimer(liveStrokeInterval)
+	}
+}
+
+func (g *Game) liveStroke(player *Player, recording *strokeRecording, index int) LiveStroke {
+	return LiveStroke{
+		PlayerID:   player.ID,
+		Index:      index,
+		Width:      recording.Drawing.Width,
+		Height:     recording.Drawing.Height,
+		Stroke:     recording.Drawing.Strokes[index],
+		hiddenFrom: g.playingLater(player),
+	}
+}
+
+// Everyone still to play on the journey the player is drawing on this round.
+func (g *Game) playingLater(player *Player) map[string]bool {
+	later := make(map[string]bool)
+	if journey := g.journeyForPlayer(player); journey != nil {
+		for _, laterPlayer := range journey.Order[g.Round+1:] {
+			later[laterPlayer.ID] = true
+		}
+	}
+	return later
+}
+
+// The channel the next batch of live strokes is due on. Nil (so never ready) when there's nothing waiting.
+func (g *Game) liveStrokesDue() <-chan time.Time {
+	if g.liveTimer == nil {
+		return nil
+	}
+	return g.liveTimer.C
+}
+
+// Send everyone watching the strokes they're allowed to see.
+func (g *Game) sendLiveStrokes() {
+	strokes := g.liveStrokes
+	g.stopLiveStrokes()
+	if len(strokes) == 0 {
+		return
+	}
+	for _, watcher := range g.liveWatchers() {
+		g.sendLiveStrokesTo(watcher, strokes)
+	}
+}
+
+func (g *Game) sendLiveStrokesTo(watcher *Player, strokes []LiveStroke) {
+	visible := make([]LiveStroke, 0, len(strokes))
+	for _, stroke := range strokes {
+		if stroke.PlayerID != watcher.ID && !stroke.hiddenFrom[watcher.ID] {
+			visible = append(visible, stroke)
+		}
+	}
+	if len(visible) > 0 {
+		g.sendLossy(watcher, "liveStrokes", LiveStrokesMessage{Round: g.Round, Strokes: visible})
+	}
+}
+
+// Connected spectators, and connected players who aren't still playing this round.
+func (g *Game) liveWatchers() []*Player {
+	watchers := make([]*Player, 0)
+	for _, spectator := range g.Spectators {
+		if g.connected[spectator.ID] {
+			watchers = append(watchers, spectator)
+		}
+	}
+	stillPlaying := make(map[string]bool)
+	for _, player := range g.waitingFor() {
+		stillPlaying[player.ID] = true
+	}
+	for _, player := range g.Players {
+		if g.connected[player.ID] && !stillPlaying[player.ID] {
+			watchers = append(watchers, player)
+		}
+	}
+	return watchers
+}
+
+// Everything drawn so far this round, for someone who has only just turned up to watch.
+func (g *Game) sendLiveDrawingsSoFar(watcher *Player) {
+	if !g.Settings.LiveDrawings || g.Stage != GAME_RUNNING {
+		return
+	}
+	strokes := make([]LiveStroke, 0)
+	for playerID, recording := range g.recordings {
+		player, found := g.PlayerMap[playerID]
+		if !found || recording.Round != g.Round {
+			continue
+		}
+		for i := range recording.Drawing.Strokes {
+			strokes = append(strokes, g.liveStroke(player, recording, i))
+		}
+	}
+	g.sendLiveStrokesTo(watcher, strokes)
+}
+
+// Drop anything still waiting to go out, and stop the clock on it.
+func (g *Game) stopLiveStrokes() {
+	if g.liveTimer != nil {
+		g.liveTimer.Stop()
+		g.liveTimer = nil
+	}
+	g.liveStrokes = nil
+}
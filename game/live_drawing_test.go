@@ -0,0 +1,186 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// Live strokes sent to the player, batch by batch.
+func liveStrokesSentTo(t *testing.T, sent []sentMessage, player *Player) []LiveStrokesMessage {
+	t.Helper()
+	batches := make([]LiveStrokesMessage, 0)
+	for _, message := range sent {
+		if message.target != player || message.msgType != "liveStrokes" {
+			continue
+		}
+		var batch LiveStrokesMessage
+		message.decode(t, &batch)
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func liveDrawingGame(t *testing.T, rounds int) *Game {
+	settings := DefaultSettings()
+	settings.LiveDrawings = true
+	settings.Rounds = rounds
+	g := newTestGame(t, settings, 4)
+	go g.run()
+	execWithin(t, g, func() {
+		for _, player := range g.Players {
+			g.connected[player.ID] = true
+		}
+		if err := g.StartGame(); err != nil {
+			t.Error(err)
+		}
+		takeSent(t, g)
+	})
+	return g
+}
+
+func TestLiveStrokesAreHiddenFromEveryoneStillToPlayTheJourney(t *testing.T) {
+	// Journeys go round the four players, three rounds each, so player 0's drawing is still to be seen by players 1
+	// and 2. Only player 3 can watch it.
+	g := liveDrawingGame(t, 3)
+	p0, p1, p2, p3 := g.Players[0], g.Players[1], g.Players[2], g.Players[3]
+	spectator := &Player{ID: "spectator"}
+	var messages []sentMessage
+	execWithin(t, g, func() {
+		g.Spectators = map[string]*Player{spectator.ID: spectator}
+		g.connected[spectator.ID] = true
+		for _, player := range []*Player{p2, p3} {
+			g.HandleMessage(testClientMessage(t, player, "", "drawing", DrawingMessage{
+				Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}},
+			}))
+		}
+		g.HandleMessage(testClientMessage(t, p0, "", "stroke", StrokeMessage{Width: 100, Height: 100, Stroke: testStroke(1)}))
+		g.sendLiveStrokes()
+		messages = takeSent(t, g)
+	})
+	if batches := liveStrokesSentTo(t, messages, p3); len(batches) != 1 || batches[0].Strokes[0].PlayerID != p0.ID {
+		t.Errorf("player 3 was sent %+v", batches)
+	}
+	if batches := liveStrokesSentTo(t, messages, spectator); len(batches) != 1 {
+		t.Errorf("spectator was sent %+v", batches)
+	}
+	for _, player := range []*Player{p0, p1, p2} {
+		if batches := liveStrokesSentTo(t, messages, player); len(batches) != 0 {
+			t.Errorf("%v was sent %+v", player.ID, batches)
+		}
+	}
+
+	// Reconnecting catches finished players up, with the same strokes hidden.
+	execWithin(t, g, func() {
+		g.reconnectPlayer(p2)
+		g.reconnectPlayer(p3)
+		messages = takeSent(t, g)
+	})
+	if batches := liveStrokesSentTo(t, messages, p2); len(batches) != 0 {
+		t.Errorf("reconnecting player 2 was sent %+v", batches)
+	}
+	if batches := liveStrokesSentTo(t, messages, p3); len(batches) != 1 || len(batches[0].Strokes) != 1 {
+		t.Errorf("reconnecting player 3 was sent %+v", batches)
+	}
+}
+
+func TestLiveStrokesAreBatched(t *testing.T) {
+	g := liveDrawingGame(t, 2)
+	p0, p3 := g.Players[0], g.Players[3]
+	execWithin(t, g, func() {
+		g.HandleMessage(testClientMessage(t, p3, "", "drawing", DrawingMessage{
+			Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}},
+		}))
+		for i := 0; i < 3; i++ {
+			g.HandleMessage(testClientMessage(t, p0, "", "stroke", StrokeMessage{Width: 100, Height: 100, Stroke: testStroke(i)}))
+		}
+		takeSent(t, g)
+	})
+	time.Sleep(2 * liveStrokeInterval)
+	var messages []sentMessage
+	execWithin(t, g, func() { messages = takeSent(t, g) })
+	batches := liveStrokesSentTo(t, messages, p3)
+	if len(batches) != 1 || len(batches[0].Strokes) != 3 {
+		t.Fatalf("player 3 was sent %+v, want one batch of three strokes", batches)
+	}
+	for i, stroke := range batches[0].Strokes {
+		if stroke.Index != i {
+			t.Errorf("stroke %v came with index %v", i, stroke.Index)
+		}
+	}
+}
+
+func TestLiveStrokesAreSentBeforeTheRoundEnds(t *testing.T) {
+	g := liveDrawingGame(t, 2)
+	var messages []sentMessage
+	var timerRunning bool
+	var stage GameStage
+	execWithin(t, g, func() {
+		p0, p3 := g.Players[0], g.Players[3]
+		g.HandleMessage(testClientMessage(t, p3, "", "drawing", DrawingMessage{
+			Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}},
+		}))
+		for i := 0; i < 2; i++ {
+			g.HandleMessage(testClientMessage(t, p0, "", "stroke", StrokeMessage{Width: 100, Height: 100, Stroke: testStroke(i)}))
+		}
+		// Everyone finishes before the batch is due.
+		for _, player := range g.Players[1:3] {
+			g.HandleMessage(testClientMessage(t, player, "", "drawing", DrawingMessage{
+				Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}},
+			}))
+		}
+		g.HandleMessage(testClientMessage(t, p0, "", "drawing", DrawingMessage{Streamed: true}))
+		messages = takeSent(t, g)
+		// Guessing is the last round, which leads into the review.
+		for _, player := range g.Players {
+			g.HandleMessage(testClientMessage(t, player, "", "guess", GuessMessage{Guess: "a guess"}))
+		}
+		timerRunning = g.liveTimer != nil || len(g.liveStrokes) > 0
+		stage = g.Stage
+	})
+	batches := liveStrokesSentTo(t, messages, g.Players[3])
+	if len(batches) != 1 || batches[0].Round != 0 || len(batches[0].Strokes) != 2 {
+		t.Errorf("player 3 was sent %+v, want the two strokes from round 0", batches)
+	}
+	if stage != GAME_REVIEWING {
+		t.Fatalf("game is %v, want it reviewing", stage)
+	}
+	if timerRunning {
+		t.Error("live strokes are still waiting to go out in the review")
+	}
+}
+
+func TestLiveStrokesDontCrowdOutTheNextRound(t *testing.T) {
+	settings := DefaultSettings()
+	settings.LiveDrawings = true
+	settings.MaxPlayers = maxMaxPlayers
+	settings.Rounds = 2
+	g := NewGame(nil, NewMemoryBlobStore(), settings)
+	players := make([]*testClient, maxMaxPlayers)
+	for i := range players {
+		players[i] = joinTestClient(t, g)
+	}
+	for i := 0; i < maxSpectators; i++ {
+		spectateTestClient(t, g)
+	}
+	players[0].send("start", "start", nil)
+	for _, c := range players {
+		c.expect("word", nil)
+	}
+	// Everyone streams their drawing in, so the end of the round has a batch of strokes for every watcher to go out
+	// along with the next round.
+	for _, c := range players {
+		for i := 0; i < 3; i++ {
+			c.send("", "stroke", StrokeMessage{Width: 100, Height: 100, Stroke: testStroke(i)})
+		}
+	}
+	for _, c := range players {
+		c.send("draw", "drawing", DrawingMessage{Streamed: true})
+	}
+	for _, c := range players {
+		var drawing DrawingRoundMessage
+		c.expect("drawing", &drawing)
+		if drawing.Round != 1 || len(drawing.Strokes) == 0 {
+			t.Errorf("%v was given %+v to guess", c.player.ID, drawing)
+		}
+	}
+}
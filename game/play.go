@@ -1,5 +1,23 @@
 package game
 
+import "encoding/json"
+
+// What kind of play players make in a round.
+type PlayKind string
+
+const (
+	PLAY_DRAWING PlayKind = "drawing"
+	PLAY_GUESS   PlayKind = "guess"
+)
+
+// Journeys start with a word, so even rounds are drawn and odd rounds are guessed.
+func playKindForRound(round int) PlayKind {
+	if round%2 == 0 {
+		return PLAY_DRAWING
+	}
+	return PLAY_GUESS
+}
+
 // The wild ride the drawings and guesses hopefully go through!
 type WordJourney struct {
 	Order []*Player  `json:"playOrder"`
@@ -27,15 +45,64 @@ func (g *Word) GetPlayer() *Player {
 	return g.Player
 }
 
+// How a drawing was sent, and so how it's stored.
+type DrawingFormat string
+
+const (
+	// An image the client sent, checked and re-encoded as a PNG.
+	DRAWING_PNG DrawingFormat = "png"
+	// JSON StrokeDrawing.
+	DRAWING_STROKES DrawingFormat = "strokes"
+)
+
 type Drawing struct {
-	Drawing string  `json:"drawing"`
-	Player  *Player `json:"player"`
+	// Where the drawing is in the game's BlobStore, empty for a blank drawing.
+	Ref    string        `json:"ref"`
+	Format DrawingFormat `json:"format,omitempty"`
+	Player *Player       `json:"player"`
+	// When each stroke was made, for drawings sent as strokes.
+	Timeline *DrawingTimeline `json:"timeline,omitempty"`
+	// Drawings sent as strokes are rendered once they're in, this is where the PNG is kept.
+	PNGRef string `json:"pngRef,omitempty"`
 }
 
 func (d *Drawing) GetPlay() string {
-	return d.Drawing
+	return d.Ref
 }
 
 func (d *Drawing) GetPlayer() *Player {
 	return d.Player
 }
+
+// Plays are stored as an interface, so work out which kind each one is when reading a journey back in.
+func (j *WordJourney) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Order []*Player         `json:"playOrder"`
+		Plays []json.RawMessage `json:"gamePlays"`
+	}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	j.Order = raw.Order
+	j.Plays = make([]GamePlay, 0, len(raw.Plays))
+	for _, rawPlay := range raw.Plays {
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(rawPlay, &fields)
+		if err != nil {
+			return err
+		}
+		var play GamePlay
+		if _, hasRef := fields["ref"]; hasRef {
+			play = &Drawing{}
+		} else {
+			play = &Word{}
+		}
+		err = json.Unmarshal(rawPlay, play)
+		if err != nil {
+			return err
+		}
+		j.Plays = append(j.Plays, play)
+	}
+	return nil
+}
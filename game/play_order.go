@@ -0,0 +1,78 @@
+package game
+
+import "math/rand"
+
+// How players are arranged on each journey.
+type PlayOrder string
+
+const (
+	// Everyone passes to the same neighbour every round.
+	PLAY_ORDER_ROTATION PlayOrder = "rotation"
+	// A balanced Latin square, so who passes to whom changes from round to round. Perfectly balanced for even numbers
+	// of players, close enough for odd.
+	PLAY_ORDER_LATIN_SQUARE PlayOrder = "latinSquare"
+	// A random Latin square.
+	PLAY_ORDER_SHUFFLED PlayOrder = "shuffled"
+)
+
+// A play order strategy gives the order players play each journey in: orders[journey][round]. There's one journey
+// per player, and every player must appear exactly once in every round.
+type playOrderStrategy func(players []*Player, rounds int, rng *rand.Rand) [][]*Player
+
+var playOrderStrategies = map[PlayOrder]playOrderStrategy{
+	PLAY_ORDER_ROTATION:     rotationPlayOrder,
+	PLAY_ORDER_LATIN_SQUARE: latinSquarePlayOrder,
+	PLAY_ORDER_SHUFFLED:     shuffledPlayOrder,
+}
+
+// Players take turns round the table, going round again if there are more rounds than players.
+func rotationPlayOrder(players []*Player, rounds int, rng *rand.Rand) [][]*Player {
+	n := len(players)
+	orders := make([][]*Player, n)
+	for journey := range orders {
+		for round := 0; round < rounds; round++ {
+			orders[journey] = append(orders[journey], players[(journey+round)%n])
+		}
+	}
+	return orders
+}
+
+// Williams' design: the first journey goes 0, 1, n-1, 2, n-2, ... and every other journey is shifted along from it.
+// With an even number of players each step is a different distance round the table, so no one passes to the same
+// person twice until the rounds go round again. With an odd number a few distances come up twice, so a few people do.
+func latinSquarePlayOrder(players []*Player, rounds int, rng *rand.Rand) [][]*Player {
+	n := len(players)
+	first := make([]int, n)
+	low, high := 1, n-1
+	for i := 1; i < n; i++ {
+		if i%2 == 1 {
+			first[i] = low
+			low++
+		} else {
+			first[i] = high
+			high--
+		}
+	}
+	orders := make([][]*Player, n)
+	for journey := range orders {
+		for round := 0; round < rounds; round++ {
+			orders[journey] = append(orders[journey], players[(first[round%n]+journey)%n])
+		}
+	}
+	return orders
+}
+
+// A cyclic Latin square with its players, journeys and rounds all shuffled, which keeps it a Latin square.
+func shuffledPlayOrder(players []*Player, rounds int, rng *rand.Rand) [][]*Player {
+	n := len(players)
+	shuffled := rng.Perm(n)
+	journeyOffsets := rng.Perm(n)
+	roundOffsets := rng.Perm(n)
+	orders := make([][]*Player, n)
+	for journey := range orders {
+		for round := 0; round < rounds; round++ {
+			orders[journey] = append(orders[journey], players[shuffled[(journeyOffsets[journey]+roundOffsets[round%n])%n]])
+		}
+	}
+	return orders
+}
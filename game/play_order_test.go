@@ -0,0 +1,96 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func testPlayers(n int) []*Player {
+	players := make([]*Player, n)
+	for i := range players {
+		players[i] = &Player{ID: fmt.Sprintf("player-%v", i)}
+	}
+	return players
+}
+
+func TestPlayOrdersAreLatinSquares(t *testing.T) {
+	for order, strategy := range playOrderStrategies {
+		for n := 3; n <= 12; n++ {
+			for _, rounds := range []int{2, n - 1, n, n + 1, 2*n + 1} {
+				t.Run(fmt.Sprintf("%v/%v players/%v rounds", order, n, rounds), func(t *testing.T) {
+					orders := strategy(testPlayers(n), rounds, rand.New(rand.NewSource(int64(n))))
+					if len(orders) != n {
+						t.Fatalf("%v journeys for %v players", len(orders), n)
+					}
+					for journey, journeyOrder := range orders {
+						if len(journeyOrder) != rounds {
+							t.Fatalf("journey %v is %v rounds long, want %v", journey, len(journeyOrder), rounds)
+						}
+					}
+					for round := 0; round < rounds; round++ {
+						seen := make(map[string]bool)
+						for _, journeyOrder := range orders {
+							player := journeyOrder[round]
+							if seen[player.ID] {
+								t.Fatalf("%v plays twice in round %v", player.ID, round)
+							}
+							seen[player.ID] = true
+						}
+					}
+					// No one should see the same journey twice until everyone has had a go.
+					for journey, journeyOrder := range orders {
+						seen := make(map[string]bool)
+						for round := 0; round < rounds && round < n; round++ {
+							player := journeyOrder[round]
+							if seen[player.ID] {
+								t.Fatalf("%v plays journey %v twice in the first %v rounds", player.ID, journey, n)
+							}
+							seen[player.ID] = true
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// Count how many times each player passes a journey on to each other player, over the first lap of rounds.
+func countPasses(orders [][]*Player, rounds int) map[[2]string]int {
+	passes := make(map[[2]string]int)
+	for _, journeyOrder := range orders {
+		for round := 1; round < rounds && round < len(orders); round++ {
+			passes[[2]string{journeyOrder[round-1].ID, journeyOrder[round].ID}]++
+		}
+	}
+	return passes
+}
+
+func TestLatinSquareNeighboursDontRepeat(t *testing.T) {
+	for n := 3; n <= 12; n++ {
+		for _, rounds := range []int{2, n - 1, n, n + 1, 2*n + 1} {
+			orders := latinSquarePlayOrder(testPlayers(n), rounds, nil)
+			// Every distance round the table comes up once for even numbers of players, and twice for odd.
+			most := 1
+			if n%2 == 1 {
+				most = 2
+			}
+			for pass, count := range countPasses(orders, rounds) {
+				if count > most {
+					t.Errorf("%v players, %v rounds: %v passes to %v %v times", n, rounds, pass[0], pass[1], count)
+				}
+			}
+		}
+	}
+}
+
+func TestRotationAlwaysPassesToTheSameNeighbour(t *testing.T) {
+	for n := 3; n <= 12; n++ {
+		orders := rotationPlayOrder(testPlayers(n), n, nil)
+		for pass, count := range countPasses(orders, n) {
+			if count != n-1 {
+				t.Errorf("%v players: %v passes to %v %v times, want every time", n, pass[0], pass[1], count)
+			}
+		}
+	}
+}
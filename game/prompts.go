@@ -0,0 +1,114 @@
+package game
+
+import (
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const maxPromptLength = 60
+
+// Time to write a starting prompt for someone else to draw.
+type WritePromptMessage struct{}
+
+// A starting prompt written by a player.
+type PromptMessage struct {
+	Prompt string `json:"prompt"`
+}
+
+// Ask everyone for a prompt, and start the clock on them writing it.
+func (g *Game) beginPrompting() {
+	g.Prompts = make(map[string]string)
+	g.startRoundTimer(g.Settings.promptDuration())
+	g.broadcast("writePrompt", WritePromptMessage{})
+	if !g.Deadline.IsZero() {
+		g.sendCountdown()
+	}
+	g.sendProgress()
+}
+
+func (m *PromptMessage) handle(g *Game, player *Player) error {
+	if g.Stage != GAME_PROMPTING {
+		return newProtocolError(ErrCodeWrongStage, "prompts aren't being written")
+	}
+	if _, written := g.Prompts[player.ID]; written {
+		return newProtocolError(ErrCodeAlreadyPlayed, "you've already written a prompt")
+	}
+	prompt := strings.TrimSpace(m.Prompt)
+	if len(prompt) < 1 || len(prompt) > maxPromptLength {
+		return newProtocolError(ErrCodeInvalid, "prompts must be between 1 and %v characters", maxPromptLength)
+	}
+	g.Prompts[player.ID] = prompt
+	log.WithFields(log.Fields{"gameID": g.ID, "playerID": player.ID}).Debug("prompt written")
+	if len(g.Prompts) == len(g.Players) {
+		g.beginPlay()
+	} else {
+		g.sendProgress()
+	}
+	return nil
+}
+
+// Match up written prompts with journeys so nobody sees their own prompt come back to them. Ideally the author plays
+// no part in the journey their prompt starts, failing that they're the last to get to it. When there are at least as
+// many rounds as players everyone plays every journey, and the last to get to it do so in round len(Players)-1.
+// Journeys nobody's prompt can safely start get nil, and a generated word instead.
+func (g *Game) promptAuthors() []*Player {
+	// Where on each journey each player first plays, -1 if they don't.
+	firstPlay := make([]map[string]int, len(g.Journeys))
+	for i, journey := range g.Journeys {
+		firstPlay[i] = make(map[string]int)
+		for round, player := range journey.Order {
+			if round >= g.Limit {
+				break
+			}
+			if _, seen := firstPlay[i][player.ID]; !seen {
+				firstPlay[i][player.ID] = round
+			}
+		}
+	}
+	// The authors who could start each journey, best first.
+	candidates := make([][]*Player, len(g.Journeys))
+	for i := range g.Journeys {
+		latest := 0
+		for _, round := range firstPlay[i] {
+			if round > latest {
+				latest = round
+			}
+		}
+		lastToPlay := make([]*Player, 0)
+		for _, player := range g.Players {
+			if _, written := g.Prompts[player.ID]; !written {
+				continue
+			}
+			round, plays := firstPlay[i][player.ID]
+			if !plays {
+				candidates[i] = append(candidates[i], player)
+			} else if round == latest && round > 0 {
+				lastToPlay = append(lastToPlay, player)
+			}
+		}
+		candidates[i] = append(candidates[i], lastToPlay...)
+	}
+	// Bipartite matching, each author's prompt is only used once.
+	authors := make([]*Player, len(g.Journeys))
+	journeyFor := make(map[string]int)
+	var assign func(journey int, tried map[string]bool) bool
+	assign = func(journey int, tried map[string]bool) bool {
+		for _, author := range candidates[journey] {
+			if tried[author.ID] {
+				continue
+			}
+			tried[author.ID] = true
+			other, taken := journeyFor[author.ID]
+			if !taken || assign(other, tried) {
+				authors[journey] = author
+				journeyFor[author.ID] = journey
+				return true
+			}
+		}
+		return false
+	}
+	for i := range g.Journeys {
+		assign(i, make(map[string]bool))
+	}
+	return authors
+}
@@ -0,0 +1,96 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPromptAuthorsDontPlayTheirOwnJourneyEarly(t *testing.T) {
+	for _, order := range []PlayOrder{PLAY_ORDER_ROTATION, PLAY_ORDER_LATIN_SQUARE, PLAY_ORDER_SHUFFLED} {
+		for players := MinPlayers; players <= 8; players++ {
+			for _, rounds := range []int{0, 2, players - 1, players + 1, 2 * players} {
+				for _, endOn := range []PlayKind{"", PLAY_DRAWING, PLAY_GUESS} {
+					t.Run(fmt.Sprintf("%v/%v players/%v rounds/end on %q", order, players, rounds, endOn), func(t *testing.T) {
+						settings := DefaultSettings()
+						settings.PlayOrder = order
+						settings.Rounds = rounds
+						settings.EndOn = endOn
+						settings.CustomPrompts = true
+						g := newTestGame(t, settings, players)
+						g.Limit = calculateLimit(players, &g.Settings)
+						g.Prompts = make(map[string]string)
+						for _, player := range g.Players {
+							g.Prompts[player.ID] = "prompt by " + player.ID
+						}
+						g.startJourneys()
+						// Nobody can get to a journey later than the last round of the first time round everyone.
+						lastRound := g.Limit - 1
+						if lastRound > players-1 {
+							lastRound = players - 1
+						}
+						used := make(map[string]bool)
+						for i, journey := range g.Journeys {
+							author := journey.Plays[0].(*Word).Player
+							if author == nil {
+								t.Errorf("journey %v of %v starts with a generated word", i, g.Limit)
+								continue
+							}
+							if used[author.ID] {
+								t.Errorf("%v's prompt was used twice", author.ID)
+							}
+							used[author.ID] = true
+							for round, player := range journey.Order[:lastRound] {
+								if player == author {
+									t.Errorf("%v plays their own prompt's journey in round %v of %v", author.ID, round, g.Limit)
+								}
+							}
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestPromptAuthorsUsedWithRotation(t *testing.T) {
+	// Everyone plays every journey, but the last player on each can still have written its prompt.
+	g := newTestGame(t, DefaultSettings(), 5)
+	g.Limit = calculateLimit(5, &g.Settings)
+	g.Prompts = make(map[string]string)
+	for _, player := range g.Players {
+		g.Prompts[player.ID] = "prompt by " + player.ID
+	}
+	g.startJourneys()
+	for i, journey := range g.Journeys {
+		author := journey.Plays[0].(*Word).Player
+		if author != journey.Order[g.Limit-1] {
+			t.Errorf("journey %v starts with a prompt by %v, want the last player %v", i, author, journey.Order[g.Limit-1].ID)
+		}
+	}
+}
+
+func TestPromptedGamesWithExtraRoundsStartFromPrompts(t *testing.T) {
+	// Ending on a guess with three players needs a fourth round, so everyone plays every journey.
+	settings := DefaultSettings()
+	settings.CustomPrompts = true
+	settings.EndOn = PLAY_GUESS
+	g := newTestGame(t, settings, 3)
+	if err := g.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	if g.Limit != 4 {
+		t.Fatalf("limit is %v, want 4", g.Limit)
+	}
+	for _, player := range g.Players {
+		g.HandleMessage(testClientMessage(t, player, "", "prompt", PromptMessage{Prompt: "prompt by " + player.ID}))
+	}
+	if g.Stage != GAME_RUNNING {
+		t.Fatalf("game is %v, want it running", g.Stage)
+	}
+	for i, journey := range g.Journeys {
+		start := journey.Plays[0].(*Word)
+		if start.Player == nil || start.Word != "prompt by "+start.Player.ID {
+			t.Errorf("journey %v starts with %+v", i, start)
+		}
+	}
+}
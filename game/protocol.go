@@ -0,0 +1,121 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The WebSocket protocol spoken between the server and clients.
+//
+// Every message in either direction is a JSON envelope {"v": <version>, "type": <message type>, "data": <payload>}.
+// Client messages are decoded into the struct registered for their type in clientMessages (see handlers.go) and
+// server messages are the *Message structs in updates.go.
+//
+// Client messages can also carry an "id". Every client message is answered, to just that player, with either an
+// "ack" or an "error" message quoting the same ID back, so clients can tell which of their messages it's about. Strokes
+// come in too thick and fast to acknowledge each one, so they're only answered when there's an error. The drawing
+// message that finishes a streamed drawing is acknowledged as usual, and says it all got there.
+
+// The newest version of the protocol, and all the ones the server can still speak.
+const ProtocolVersion = 1
+
+var supportedProtocolVersions = []int{1}
+
+var errNoCommonProtocol = errors.New("no supported protocol version offered")
+
+// Clients that don't offer a version are speaking the original unversioned protocol, with plain string payloads. Its
+// messages can't be read as any version we speak, so they're turned away rather than left failing on every message.
+var ErrLegacyProtocol = fmt.Errorf("the unversioned protocol is no longer supported, connect with ?protocol=%v", ProtocolVersion)
+
+// Pick the newest version both sides can speak, from the comma separated versions the client offered when
+// connecting.
+func NegotiateProtocol(offered string) (int, error) {
+	if strings.TrimSpace(offered) == "" {
+		return 0, ErrLegacyProtocol
+	}
+	chosen := 0
+	for _, option := range strings.Split(offered, ",") {
+		version, err := strconv.Atoi(strings.TrimSpace(option))
+		if err != nil {
+			return 0, fmt.Errorf("invalid protocol version %q", option)
+		}
+		for _, supported := range supportedProtocolVersions {
+			if version == supported && version > chosen {
+				chosen = version
+			}
+		}
+	}
+	if chosen == 0 {
+		return 0, errNoCommonProtocol
+	}
+	return chosen, nil
+}
+
+type clientEnvelope struct {
+	Version int             `json:"v"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type serverEnvelope struct {
+	Version int         `json:"v"`
+	Type    string      `json:"type"`
+	Data    interface{} `json:"data"`
+}
+
+func encodeMessage(msgType string, data interface{}) ([]byte, error) {
+	return json.Marshal(serverEnvelope{
+		Version: ProtocolVersion,
+		Type:    msgType,
+		Data:    data,
+	})
+}
+
+// Error codes sent back to clients in error messages.
+const (
+	ErrCodeBadMessage         = "badMessage"
+	ErrCodeUnknownType        = "unknownType"
+	ErrCodeUnsupportedVersion = "unsupportedVersion"
+	ErrCodeWrongStage         = "wrongStage"
+	ErrCodeNotAllowed         = "notAllowed"
+	ErrCodeNotHost            = "notHost"
+	ErrCodeNotYourTurn        = "notYourTurn"
+	ErrCodeAlreadyPlayed      = "alreadyPlayed"
+	ErrCodePlayerNotFound     = "playerNotFound"
+	ErrCodeInvalidName        = "invalidName"
+	ErrCodeInvalidSettings    = "invalidSettings"
+	ErrCodeNotEnoughPlayers   = "notEnoughPlayers"
+	ErrCodeSpectator          = "spectator"
+	ErrCodeInvalidDrawing     = "invalidDrawing"
+	ErrCodeInvalid            = "invalid"
+	ErrCodeInternal           = "internal"
+	// The message never reached the game, and should be sent again.
+	ErrCodeBusy = "busy"
+)
+
+// ProtocolError is something that went wrong handling a client's message, that the client should be told about.
+type ProtocolError struct {
+	Code    string
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+func newProtocolError(code string, format string, args ...interface{}) *ProtocolError {
+	return &ProtocolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Errors that aren't already a ProtocolError are something the client couldn't have done anything about.
+func asProtocolError(err error) *ProtocolError {
+	var protocolErr *ProtocolError
+	if errors.As(err, &protocolErr) {
+		return protocolErr
+	}
+	return &ProtocolError{Code: ErrCodeInternal, Message: err.Error()}
+}
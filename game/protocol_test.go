@@ -0,0 +1,58 @@
+package game
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"drawl-server/config"
+	"github.com/gorilla/websocket"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	tests := []struct {
+		offered string
+		version int
+		err     bool
+	}{
+		{"", 0, true},
+		{"1", 1, false},
+		{"1,2", 1, false},
+		{" 2 , 1 ", 1, false},
+		{"2", 0, true},
+		{"one", 0, true},
+	}
+	for _, test := range tests {
+		version, err := NegotiateProtocol(test.offered)
+		if version != test.version || (err != nil) != test.err {
+			t.Errorf("NegotiateProtocol(%q) = %v, %v; want %v, error %v", test.offered, version, err, test.version, test.err)
+		}
+	}
+	if _, err := NegotiateProtocol(""); err != ErrLegacyProtocol {
+		t.Errorf("clients not offering a version got %v, want ErrLegacyProtocol", err)
+	}
+}
+
+func TestRejectWsExplainsWhy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RejectWs(w, r, ErrLegacyProtocol.Error())
+	}))
+	defer server.Close()
+	header := http.Header{}
+	// The upgrader only lets drawl.app in.
+	header.Set("Host", config.AllowedWSOrigin)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _, err = conn.ReadMessage()
+	closeErr, ok := err.(*websocket.CloseError)
+	if !ok {
+		t.Fatalf("got %v, want the connection closed", err)
+	}
+	if closeErr.Code != websocket.CloseProtocolError || closeErr.Text != ErrLegacyProtocol.Error() {
+		t.Errorf("closed with %v %q", closeErr.Code, closeErr.Text)
+	}
+}
@@ -0,0 +1,159 @@
+package game
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Drawings can be streamed a stroke at a time while they're being drawn, rather than sent all at once at the end. The
+// server records when each stroke turned up, so the review can play back how the drawing was made.
+
+// One stroke of the drawing in progress. The first stroke of a drawing says how big the canvas is.
+type StrokeMessage struct {
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Stroke Stroke `json:"stroke"`
+}
+
+// When each stroke of a drawing was made, for playing it back.
+type DrawingTimeline struct {
+	// Milliseconds from the start of the first stroke to the end of the last.
+	Duration int            `json:"duration"`
+	Strokes  []StrokeTiming `json:"strokes"`
+}
+
+type StrokeTiming struct {
+	// From the drawing's own timestamps, in milliseconds since it was started.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Milliseconds after the first stroke that the server got this one, if the drawing was streamed.
+	Received *int `json:"received,omitempty"`
+}
+
+// Sent to a player who reconnects part way through streaming a drawing, so they can carry on from where they were.
+type RecordingMessage struct {
+	Round   int            `json:"round"`
+	Drawing *StrokeDrawing `json:"drawing"`
+}
+
+// A drawing being streamed in by a player during the current round.
+type strokeRecording struct {
+	Round   int            `json:"round"`
+	Drawing *StrokeDrawing `json:"drawing"`
+	Started time.Time      `json:"started"`
+	// Milliseconds after Started that each stroke arrived.
+	Received []int `json:"received"`
+	// Running totals for the whole drawing, so they needn't be counted up again for every stroke.
+	points int
+	ink    int
+}
+
+func (m *StrokeMessage) handle(g *Game, player *Player) error {
+	_, err := g.journeyToPlay(player, PLAY_DRAWING)
+	if err != nil {
+		return err
+	}
+	recording := g.recordings[player.ID]
+	if recording == nil || recording.Round != g.Round {
+		recording = &strokeRecording{
+			Round:   g.Round,
+			Drawing: &StrokeDrawing{Width: m.Width, Height: m.Height, Strokes: make([]Stroke, 0)},
+			Started: time.Now(),
+		}
+		err = recording.Drawing.Validate()
+		if err != nil {
+			return newProtocolError(ErrCodeInvalidDrawing, "%v", err)
+		}
+		if g.recordings == nil {
+			g.recordings = make(map[string]*strokeRecording)
+		}
+		g.recordings[player.ID] = recording
+	} else if (m.Width != 0 || m.Height != 0) && (m.Width != recording.Drawing.Width || m.Height != recording.Drawing.Height) {
+		return newProtocolError(ErrCodeInvalidDrawing, "the canvas can't change size part way through a drawing")
+	}
+	drawing := recording.Drawing
+	if len(drawing.Strokes) >= maxStrokes {
+		return newProtocolError(ErrCodeInvalidDrawing, "drawing can have at most %v strokes", maxStrokes)
+	}
+	if recording.points+len(m.Stroke.Points) > maxStrokePoints {
+		return newProtocolError(ErrCodeInvalidDrawing, "drawing can have at most %v points", maxStrokePoints)
+	}
+	err = drawing.validateStroke(len(drawing.Strokes), m.Stroke)
+	if err != nil {
+		return newProtocolError(ErrCodeInvalidDrawing, "%v", err)
+	}
+	ink := m.Stroke.ink()
+	if recording.ink+ink > maxInk {
+		return newProtocolError(ErrCodeInvalidDrawing, "%v", errTooMuchInk)
+	}
+	drawing.Strokes = append(drawing.Strokes, m.Stroke)
+	recording.points += len(m.Stroke.Points)
+	recording.ink += ink
+	recording.Received = append(recording.Received, int(time.Since(recording.Started)/time.Millisecond))
+	g.relayStroke(player, recording)
+	return nil
+}
+
+// Count the totals back up for a recording read back in from a snapshot.
+func (r *strokeRecording) recount() {
+	r.points, r.ink = 0, 0
+	for _, stroke := range r.Drawing.Strokes {
+		r.points += len(stroke.Points)
+		r.ink += stroke.ink()
+	}
+}
+
+// The recording the player has been streaming this round, if any.
+func (g *Game) currentRecording(player *Player) *strokeRecording {
+	recording := g.recordings[player.ID]
+	if recording == nil || recording.Round != g.Round || len(recording.Drawing.Strokes) == 0 {
+		return nil
+	}
+	return recording
+}
+
+// Store a finished stroke drawing, with its timeline and a PNG of it, as the player's play.
+func (g *Game) storeStrokeDrawing(player *Player, strokes *StrokeDrawing, received []int) (*Drawing, error) {
+	data, err := json.Marshal(strokes)
+	if err != nil {
+		return nil, err
+	}
+	ref := g.putDrawing(DRAWING_STROKES, data)
+	drawing := &Drawing{Ref: ref, Format: DRAWING_STROKES, Player: player, Timeline: strokes.timeline(received)}
+	g.renderDrawing(drawing, data)
+	return drawing, nil
+}
+
+// Anyone who ran out of time part way through streaming a drawing still gets what they'd drawn so far.
+func (g *Game) recordedDrawing(player *Player) *Drawing {
+	recording := g.currentRecording(player)
+	if recording == nil {
+		return nil
+	}
+	drawing, err := g.storeStrokeDrawing(player, recording.Drawing, recording.Received)
+	if err != nil {
+		log.WithError(err).WithField("gameID", g.ID).Error("could not store recorded drawing")
+		return nil
+	}
+	return drawing
+}
+
+func (d *StrokeDrawing) timeline(received []int) *DrawingTimeline {
+	if len(d.Strokes) == 0 {
+		return nil
+	}
+	timeline := &DrawingTimeline{
+		Duration: d.Strokes[len(d.Strokes)-1].end() - d.Strokes[0].start(),
+		Strokes:  make([]StrokeTiming, 0, len(d.Strokes)),
+	}
+	for i, stroke := range d.Strokes {
+		timing := StrokeTiming{Start: stroke.start(), End: stroke.end()}
+		if i < len(received) {
+			timing.Received = &received[i]
+		}
+		timeline.Strokes = append(timeline.Strokes, timing)
+	}
+	return timeline
+}
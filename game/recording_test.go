@@ -0,0 +1,55 @@
+package game
+
+import "testing"
+
+func TestStreamedStrokesArentAcknowledged(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	player := g.Players[0]
+	go g.run()
+	var points, ink int
+	var answers []sentMessage
+	execWithin(t, g, func() {
+		if err := g.StartGame(); err != nil {
+			t.Error(err)
+			return
+		}
+		// Forget everything the start sent out.
+		takeSent(t, g)
+		for i := 0; i < 100; i++ {
+			g.HandleMessage(testClientMessage(t, player, "", "stroke", StrokeMessage{Width: 200, Height: 100, Stroke: testStroke(i)}))
+		}
+		recording := g.currentRecording(player)
+		points, ink = recording.points, recording.ink
+		recording.recount()
+		if recording.points != points || recording.ink != ink {
+			t.Errorf("running totals %v points, %v ink, counted %v and %v", points, ink, recording.points, recording.ink)
+		}
+		g.HandleMessage(testClientMessage(t, player, "done-drawing", "drawing", DrawingMessage{Streamed: true}))
+		for _, message := range takeSent(t, g) {
+			if message.target == player {
+				answers = append(answers, message)
+			}
+		}
+	})
+	if points != 200 {
+		t.Errorf("counted %v points, want 200", points)
+	}
+	if len(answers) != 1 {
+		t.Fatalf("player was sent %v messages, want just the drawing's ack: %+v", len(answers), answers)
+	}
+	var ack AckMessage
+	answers[0].decode(t, &ack)
+	if answers[0].msgType != "ack" || ack.RequestID != "done-drawing" {
+		t.Errorf("player was sent %v %s", answers[0].msgType, answers[0].data)
+	}
+}
+
+func TestBadStrokesAreStillAnswered(t *testing.T) {
+	_, clients := runTestGame(t, DefaultSettings(), 2)
+	player := clients[0]
+	player.send("start", "start", nil)
+	player.expect("word", nil)
+	stroke := Stroke{Colour: "not a colour", Width: 3, Points: []StrokePoint{{0, 0, 0}}}
+	player.send("bad-stroke", "stroke", StrokeMessage{Width: 200, Height: 100, Stroke: stroke})
+	player.expectError(ErrCodeInvalidDrawing)
+}
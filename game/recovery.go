@@ -0,0 +1,141 @@
+package game
+
+import (
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// How often running games are written out, so a restart loses at most this much play.
+const snapshotInterval = 15 * time.Second
+
+// A game as it is written to disk while still running, with the bits players don't normally get to see.
+type gameSnapshot struct {
+	*Game
+	PlayersFinished []string                    `json:"playersFinished"`
+	Locked          bool                        `json:"locked"`
+	Recordings      map[string]*strokeRecording `json:"recordings,omitempty"`
+	// How far through its random numbers the game is.
+	RandomDraws int64 `json:"randomDraws"`
+}
+
+func newGameSnapshot(game *Game) *gameSnapshot {
+	finished := make([]string, 0, len(game.PlayersFinished))
+	for _, player := range game.PlayersFinished {
+		finished = append(finished, player.ID)
+	}
+	return &gameSnapshot{
+		Game:            game,
+		PlayersFinished: finished,
+		Locked:          game.IsLocked(),
+		Recordings:      game.recordings,
+		RandomDraws:     game.randomDraws(),
+	}
+}
+
+func (s *gameSnapshot) toGame() *Game {
+	game := s.Game
+	if game == nil {
+		game = &Game{}
+	}
+	game.relinkPlayers()
+	game.setLocked(s.Locked)
+	game.seedRandom(s.RandomDraws)
+	game.recordings = s.Recordings
+	for _, recording := range game.recordings {
+		recording.recount()
+	}
+	game.PlayersFinished = make([]*Player, 0, len(s.PlayersFinished))
+	for _, playerID := range s.PlayersFinished {
+		if player, found := game.PlayerMap[playerID]; found {
+			game.PlayersFinished = append(game.PlayersFinished, player)
+		}
+	}
+	return game
+}
+
+// Decoding gives every mention of a player its own copy, point them all back at the one in Players so points and
+// names stay in sync.
+func (g *Game) relinkPlayers() {
+	g.PlayerMap = make(map[string]*Player)
+	for _, player := range g.Players {
+		g.PlayerMap[player.ID] = player
+	}
+	relink := func(player *Player) *Player {
+		if player == nil {
+			return nil
+		}
+		if known, found := g.PlayerMap[player.ID]; found {
+			return known
+		}
+		return player
+	}
+	journeys := append([]*WordJourney{}, g.Journeys...)
+	for _, previous := range g.PreviousJourneys {
+		journeys = append(journeys, previous...)
+	}
+	for _, journey := range journeys {
+		for i, player := range journey.Order {
+			journey.Order[i] = relink(player)
+		}
+		for _, play := range journey.Plays {
+			switch p := play.(type) {
+			case *Word:
+				p.Player = relink(p.Player)
+			case *Drawing:
+				p.Player = relink(p.Player)
+			}
+		}
+	}
+}
+
+func (g *Game) saveSnapshot() {
+	if g.archive == nil {
+		return
+	}
+	err := g.archive.SaveSnapshot(g)
+	if err != nil {
+		log.WithError(err).WithField("gameID", g.ID).Error("could not snapshot game")
+	}
+}
+
+func (g *Game) deleteSnapshot() {
+	if g.archive == nil {
+		return
+	}
+	err := g.archive.DeleteSnapshot(g.ID)
+	if err != nil {
+		log.WithError(err).WithField("gameID", g.ID).Error("could not delete game snapshot")
+	}
+}
+
+// Pick up any games that were still running when the server last stopped. Players get their current round again
+// when they reconnect.
+func RestoreGames(store GameStore, archive GameArchive, blobs BlobStore) error {
+	games, err := archive.LoadSnapshots()
+	if err != nil {
+		return err
+	}
+	for _, game := range games {
+		logger := log.WithField("gameID", game.ID)
+		game.archive = archive
+		game.blobs = blobs
+		if game.Stage == GAME_ENDED {
+			// Crashed between archiving and tidying up.
+			game.deleteSnapshot()
+			continue
+		}
+		err = store.RestoreGame(game)
+		if err != nil {
+			logger.WithError(err).Error("could not restore game")
+			continue
+		}
+		game.setUpChannels()
+		// Everyone has been connected before, so they're treated as reconnecting.
+		for _, player := range game.Players {
+			game.Hub.history = append(game.Hub.history, player.ID)
+		}
+		game.startRunning()
+		logger.WithField("gameStage", game.Stage).Info("restored game")
+	}
+	return nil
+}
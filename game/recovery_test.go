@@ -0,0 +1,63 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Write the game out the way it's snapshotted and read it back in.
+func restoreTestGame(t *testing.T, g *Game) *Game {
+	t.Helper()
+	data, err := json.Marshal(newGameSnapshot(g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &gameSnapshot{}
+	if err = json.Unmarshal(data, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	return snapshot.toGame()
+}
+
+// The starting words of a new set of journeys, as a rematch would get.
+func startingWords(g *Game) []string {
+	g.Limit = calculateLimit(len(g.Players), &g.Settings)
+	g.startJourneys()
+	words := make([]string, 0, len(g.Journeys))
+	for _, journey := range g.Journeys {
+		words = append(words, journey.Plays[0].GetPlay())
+	}
+	return words
+}
+
+func TestSameSeedSameGame(t *testing.T) {
+	settings := DefaultSettings()
+	settings.UniquePrompts = true
+	settings.UniqueAcrossRematches = true
+	first := newTestGame(t, settings, 4)
+	second := newTestGame(t, settings, 4)
+	for game := 0; game < 3; game++ {
+		if a, b := startingWords(first), startingWords(second); !reflect.DeepEqual(a, b) {
+			t.Errorf("game %v started with %v and %v from the same seed", game, a, b)
+		}
+	}
+}
+
+func TestRestoredGamesCarryOnFromTheSameRandomNumbers(t *testing.T) {
+	settings := DefaultSettings()
+	settings.UniquePrompts = true
+	settings.UniqueAcrossRematches = true
+	played := newTestGame(t, settings, 4)
+	firstWords := startingWords(played)
+	restored := restoreTestGame(t, played)
+	// Both games have a rematch. The restored one mustn't hand out the same words again, it should get exactly what
+	// the one that was never interrupted does.
+	rematchWords := startingWords(played)
+	if restoredWords := startingWords(restored); !reflect.DeepEqual(restoredWords, rematchWords) {
+		t.Errorf("restored game's rematch started with %v, want %v", restoredWords, rematchWords)
+	}
+	if reflect.DeepEqual(rematchWords, firstWords) {
+		t.Errorf("rematch started with the same words, %v", firstWords)
+	}
+}
@@ -0,0 +1,47 @@
+package game
+
+import (
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// Play again with the same lobby, only the host can do this once the game has ended.
+type RematchMessage struct {
+	// Carry everyone's points over into the next game.
+	KeepPoints bool `json:"keepPoints"`
+}
+
+func (m *RematchMessage) handle(g *Game, player *Player) error {
+	if !g.isHost(player) {
+		return newProtocolError(ErrCodeNotHost, "only the host can start a rematch")
+	}
+	if g.store == nil {
+		return newProtocolError(ErrCodeInternal, "game isn't registered")
+	}
+	err := g.transition(GAME_STARTING)
+	if err != nil {
+		return err
+	}
+	// Keep the last game's journeys around, so it can still be reviewed.
+	g.PreviousJourneys = append(g.PreviousJourneys, g.Journeys)
+	g.Journeys = make([]*WordJourney, 0)
+	g.PlayersFinished = make([]*Player, 0)
+	g.Round = 0
+	g.Limit = 0
+	g.Deadline = time.Time{}
+	g.Awards = nil
+	g.Prompts = nil
+	if !m.KeepPoints {
+		for _, player := range g.Players {
+			player.Points = 0
+		}
+	}
+	g.setLocked(false)
+	err = g.store.AssignJoinCode(g)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"gameID": g.ID, "joinCode": g.JoinCode}).Debug("rematch started")
+	g.sendPlayers()
+	return nil
+}
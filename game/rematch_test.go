@@ -0,0 +1,37 @@
+package game
+
+import "testing"
+
+func TestRematchSendsTheNewJoinCode(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 2)
+	store := NewMemoryStore()
+	err := store.RegisterGame(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldCode := g.CurrentJoinCode()
+	// Read the join code from outside the loop while the rematch changes it, for the race detector.
+	reads := make(chan string)
+	go func() {
+		reads <- g.CurrentJoinCode()
+	}()
+	g.exec(func() {
+		g.Stage = GAME_ENDED
+		err = (&RematchMessage{}).handle(g, g.Players[0])
+	})
+	<-reads
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCode := g.CurrentJoinCode()
+	if newCode == oldCode {
+		t.Fatalf("join code is still %v", oldCode)
+	}
+	if found, _ := store.FindGameByJoinCode(newCode); found != g {
+		t.Errorf("can't join with the new code %v", newCode)
+	}
+	var players PlayersMessage
+	for players.JoinCode != newCode {
+		clients[1].expect("players", &players)
+	}
+}
@@ -0,0 +1,169 @@
+package game
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3BlobStore keeps blobs in a bucket of anything that speaks the S3 API, AWS itself or a local stand-in like MinIO.
+// Requests are signed with AWS Signature Version 4, done by hand so we don't need the whole SDK for two calls.
+type S3BlobStore struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// Objects are kept under this prefix in the bucket.
+const s3BlobPrefix = "drawings/"
+
+// The endpoint is the base URL of the service, e.g. https://s3.eu-west-2.amazonaws.com or http://localhost:9000.
+// Buckets are addressed by path rather than subdomain, which every stand-in supports.
+func NewS3BlobStore(endpoint string, bucket string, region string, accessKey string, secretKey string) (*S3BlobStore, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpointURL.Scheme != "http" && endpointURL.Scheme != "https" {
+		return nil, fmt.Errorf("S3 endpoint %q needs to be an http or https URL", endpoint)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is needed")
+	}
+	return &S3BlobStore{
+		endpoint:  endpointURL,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *S3BlobStore) Put(data []byte) (string, error) {
+	ref := blobRef(data)
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(ref), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	s.sign(req, data, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", s3Error(resp)
+	}
+	return ref, nil
+}
+
+func (s *S3BlobStore) Get(ref string) ([]byte, error) {
+	if !ValidBlobRef(ref) {
+		return nil, ErrInvalidBlobRef
+	}
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(ref), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, nil, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (s *S3BlobStore) objectURL(ref string) string {
+	objectURL := *s.endpoint
+	objectURL.Path = strings.TrimSuffix(objectURL.Path, "/") + "/" + s.bucket + "/" + s3BlobPrefix + ref
+	return objectURL.String()
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 request failed with %v: %s", resp.Status, bytes.TrimSpace(body))
+}
+
+// Add the headers for AWS Signature Version 4, signing the host, date and payload hash.
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func (s *S3BlobStore) sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
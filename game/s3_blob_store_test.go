@@ -0,0 +1,95 @@
+package game
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Just enough of S3 to keep objects, checking requests are signed and their payload hash is right.
+func fakeS3(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") ||
+			!strings.Contains(auth, "/eu-west-2/s3/aws4_request") ||
+			!strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") {
+			t.Errorf("badly signed request: %q", auth)
+			http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			t.Errorf("payload hash %v doesn't match the body", r.Header.Get("X-Amz-Content-Sha256"))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			object, found := objects[r.URL.Path]
+			if !found {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Write(object)
+		}
+	}))
+}
+
+func TestS3BlobStore(t *testing.T) {
+	server := fakeS3(t)
+	defer server.Close()
+	store, err := NewS3BlobStore(server.URL, "bucket", "eu-west-2", "access", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("a drawing")
+	ref, err := store.Put(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != blobRef(data) {
+		t.Errorf("got ref %v, want %v", ref, blobRef(data))
+	}
+	got, err := store.Get(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("got %q back, want %q", got, data)
+	}
+
+	if _, err = store.Get(blobRef([]byte("never stored"))); err != ErrBlobNotFound {
+		t.Errorf("missing drawing gave %v, want %v", err, ErrBlobNotFound)
+	}
+	if _, err = store.Get("../../etc/passwd"); err != ErrInvalidBlobRef {
+		t.Errorf("bad ref gave %v, want %v", err, ErrInvalidBlobRef)
+	}
+}
+
+func TestS3BlobStoreReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "SlowDown", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	store, err := NewS3BlobStore(server.URL, "bucket", "eu-west-2", "access", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Put([]byte("a drawing")); err == nil || !strings.Contains(err.Error(), "SlowDown") {
+		t.Errorf("failed put gave %v", err)
+	}
+	if _, err = store.Get(blobRef([]byte("a drawing"))); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("failed get gave %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type ScoringMode string
+
+const (
+	// Players can award each other as many points as they like.
+	SCORING_AWARDS ScoringMode = "awards"
+	// Each player can only award any other player once.
+	SCORING_ONE_AWARD_EACH ScoringMode = "oneAwardEach"
+	// No points at all.
+	SCORING_NONE ScoringMode = "none"
+)
+
+// GameSettings are chosen by the host before the game starts.
+type GameSettings struct {
+	// Number of rounds, zero for one per player.
+	Rounds int `json:"rounds"`
+	// Seconds players get for each drawing and guessing round, zero to wait forever.
+	DrawTime  int `json:"drawTime"`
+	GuessTime int `json:"guessTime"`
+	// Word packs starting words are picked from.
+	WordPacks []string `json:"wordPacks"`
+	// Packs marked NSFW can only be used if the host says so.
+	AllowNSFW bool `json:"allowNSFW"`
+	// Chance (0 to 1) of a starting word being about another player.
+	ContextWordChance float64     `json:"contextWordChance"`
+	MaxPlayers        int         `json:"maxPlayers"`
+	ScoringMode       ScoringMode `json:"scoringMode"`
+	// Don't use the same starting word twice in a game, or in any game played by this lobby.
+	UniquePrompts         bool `json:"uniquePrompts"`
+	UniqueAcrossRematches bool `json:"uniqueAcrossRematches"`
+	// What kind of play journeys finish on, extra rounds are added to get there. Empty for whatever the number of
+	// rounds happens to land on.
+	EndOn PlayKind `json:"endOn"`
+	// How players are arranged on each journey.
+	PlayOrder PlayOrder `json:"playOrder"`
+	// Seed for everything random in the game, zero to pick one at random. Handy for replaying a game exactly.
+	Seed int64 `json:"seed"`
+	// Players write the starting prompts themselves, with this many seconds to do it.
+	CustomPrompts bool `json:"customPrompts"`
+	PromptTime    int  `json:"promptTime"`
+	// Show drawings streamed in stroke by stroke to spectators and players who have finished, as they're drawn.
+	LiveDrawings bool `json:"liveDrawings"`
+}
+
+// Fewest players a game can start with.
+const MinPlayers = 2
+
+const (
+	maxRounds     = 30
+	maxRoundTime  = 10 * 60
+	maxMaxPlayers = 30
+)
+
+func DefaultSettings() GameSettings {
+	return GameSettings{
+		Rounds:            0,
+		DrawTime:          90,
+		GuessTime:         30,
+		WordPacks:         []string{defaultWordPack},
+		ContextWordChance: 0.1,
+		MaxPlayers:        12,
+		ScoringMode:       SCORING_AWARDS,
+		PlayOrder:         PLAY_ORDER_ROTATION,
+		CustomPrompts:     false,
+		PromptTime:        45,
+	}
+}
+
+// Check the settings make sense, so a game can actually be played with them.
+func (s *GameSettings) Validate() error {
+	if s.Rounds < 0 || s.Rounds > maxRounds {
+		return fmt.Errorf("rounds must be between 0 and %v", maxRounds)
+	}
+	if s.DrawTime < 0 || s.DrawTime > maxRoundTime {
+		return fmt.Errorf("drawing time must be between 0 and %v seconds", maxRoundTime)
+	}
+	if s.GuessTime < 0 || s.GuessTime > maxRoundTime {
+		return fmt.Errorf("guessing time must be between 0 and %v seconds", maxRoundTime)
+	}
+	if len(s.WordPacks) == 0 {
+		return fmt.Errorf("at least one word pack must be chosen")
+	}
+	for _, id := range s.WordPacks {
+		found, nsfw := lookUpWordPack(id)
+		if !found {
+			return fmt.Errorf("unknown word pack %q", id)
+		}
+		if nsfw && !s.AllowNSFW {
+			return fmt.Errorf("word pack %q is NSFW, allow NSFW packs to use it", id)
+		}
+	}
+	if s.ContextWordChance < 0 || s.ContextWordChance > 1 {
+		return fmt.Errorf("context word chance must be between 0 and 1")
+	}
+	if s.MaxPlayers < MinPlayers || s.MaxPlayers > maxMaxPlayers {
+		return fmt.Errorf("max players must be between %v and %v", MinPlayers, maxMaxPlayers)
+	}
+	switch s.EndOn {
+	case "", PLAY_DRAWING, PLAY_GUESS:
+	default:
+		return fmt.Errorf("games can only end on a drawing or a guess, not %q", s.EndOn)
+	}
+	if _, found := playOrderStrategies[s.PlayOrder]; !found {
+		return fmt.Errorf("unknown play order %q", s.PlayOrder)
+	}
+	if s.PromptTime < 0 || s.PromptTime > maxRoundTime {
+		return fmt.Errorf("prompt writing time must be between 0 and %v seconds", maxRoundTime)
+	}
+	switch s.ScoringMode {
+	case SCORING_AWARDS, SCORING_ONE_AWARD_EACH, SCORING_NONE:
+	default:
+		return fmt.Errorf("unknown scoring mode %q", s.ScoringMode)
+	}
+	return nil
+}
+
+// How many rounds a game with this many players is played for. That's one per player unless the host chose
+// otherwise, padded so every journey is at least word, drawing, guess (unless it should end on a drawing) and ends on
+// the kind of play the host asked for. Players go round again for the extra rounds.
+func calculateLimit(players int, s *GameSettings) int {
+	limit := players
+	if s.Rounds > 0 {
+		limit = s.Rounds
+	}
+	minimum := 2
+	if s.EndOn == PLAY_DRAWING {
+		minimum = 1
+	}
+	if limit < minimum {
+		limit = minimum
+	}
+	if s.EndOn != "" && playKindForRound(limit-1) != s.EndOn {
+		limit++
+	}
+	return limit
+}
+
+func (s *GameSettings) drawDuration() time.Duration {
+	return time.Duration(s.DrawTime) * time.Second
+}
+
+func (s *GameSettings) guessDuration() time.Duration {
+	return time.Duration(s.GuessTime) * time.Second
+}
+
+func (s *GameSettings) promptDuration() time.Duration {
+	return time.Duration(s.PromptTime) * time.Second
+}
+
+// Change the game's settings, only the host can do this while in the lobby. Only the settings given are changed, the
+// rest are left as they are.
+type SettingsMessage struct {
+	Settings json.RawMessage `json:"settings"`
+}
+
+func (m *SettingsMessage) handle(g *Game, player *Player) error {
+	if !g.isHost(player) {
+		return newProtocolError(ErrCodeNotHost, "only the host can change the settings")
+	}
+	if g.Stage != GAME_STARTING {
+		return newProtocolError(ErrCodeWrongStage, "settings can only be changed in the lobby")
+	}
+	// Decoded over a copy of the current settings, so whatever isn't mentioned stays the same.
+	settings := g.Settings
+	settings.WordPacks = append([]string{}, g.Settings.WordPacks...)
+	decoder := json.NewDecoder(bytes.NewReader(m.Settings))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&settings)
+	if err != nil {
+		return newProtocolError(ErrCodeInvalidSettings, "could not read settings: %v", err)
+	}
+	err = settings.Validate()
+	if err != nil {
+		return newProtocolError(ErrCodeInvalidSettings, "%v", err)
+	}
+	if settings.MaxPlayers < len(g.Players) {
+		return newProtocolError(ErrCodeInvalidSettings, "there are already %v players", len(g.Players))
+	}
+	g.Settings = settings
+	if g.Settings.Seed != 0 && g.Settings.Seed != g.Seed {
+		g.Seed = g.Settings.Seed
+		g.seedRandom(0)
+	}
+	g.sendPlayers()
+	return nil
+}
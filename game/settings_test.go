@@ -0,0 +1,131 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCalculateLimit(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  int
+		endOn   PlayKind
+		want    int
+	}{
+		{2, 0, "", 2},
+		{3, 0, "", 3},
+		{8, 0, "", 8},
+		{5, 6, "", 6},
+		{8, 3, "", 3},
+		// Word, drawing, guess is as short as a journey gets, unless it ends on the drawing.
+		{2, 1, "", 2},
+		{2, 1, PLAY_DRAWING, 1},
+		{2, 1, PLAY_GUESS, 2},
+		// Even rounds are drawings, odd rounds are guesses, so padding adds one round at most.
+		{2, 0, PLAY_DRAWING, 3},
+		{2, 0, PLAY_GUESS, 2},
+		{3, 0, PLAY_DRAWING, 3},
+		{3, 0, PLAY_GUESS, 4},
+		{4, 0, PLAY_DRAWING, 5},
+		{4, 0, PLAY_GUESS, 4},
+		{4, 6, PLAY_DRAWING, 7},
+		{4, 6, PLAY_GUESS, 6},
+		{maxMaxPlayers, maxRounds, PLAY_DRAWING, maxRounds + 1},
+		{maxMaxPlayers, maxRounds, PLAY_GUESS, maxRounds},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v players/%v rounds/end on %q", test.players, test.rounds, test.endOn), func(t *testing.T) {
+			settings := DefaultSettings()
+			settings.Rounds = test.rounds
+			settings.EndOn = test.endOn
+			if limit := calculateLimit(test.players, &settings); limit != test.want {
+				t.Errorf("got %v rounds, want %v", limit, test.want)
+			}
+		})
+	}
+}
+
+func TestGamesNeedEnoughPlayersToStart(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), MinPlayers-1)
+	err := g.StartGame()
+	if protocolErr, ok := err.(*ProtocolError); !ok || protocolErr.Code != ErrCodeNotEnoughPlayers {
+		t.Errorf("starting with %v players gave %v", MinPlayers-1, err)
+	}
+	if g.Stage != GAME_STARTING {
+		t.Errorf("game is %v, want it still in the lobby", g.Stage)
+	}
+
+	g = newTestGame(t, DefaultSettings(), MinPlayers)
+	if err = g.StartGame(); err != nil {
+		t.Errorf("starting with %v players gave %v", MinPlayers, err)
+	}
+}
+
+func TestSettingsAreChecked(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(s *GameSettings)
+		valid  bool
+	}{
+		{"defaults", func(s *GameSettings) {}, true},
+		{"most rounds", func(s *GameSettings) { s.Rounds = maxRounds }, true},
+		{"too many rounds", func(s *GameSettings) { s.Rounds = maxRounds + 1 }, false},
+		{"negative rounds", func(s *GameSettings) { s.Rounds = -1 }, false},
+		{"untimed", func(s *GameSettings) { s.DrawTime, s.GuessTime, s.PromptTime = 0, 0, 0 }, true},
+		{"longest rounds", func(s *GameSettings) {
+			s.DrawTime, s.GuessTime, s.PromptTime = maxRoundTime, maxRoundTime, maxRoundTime
+		}, true},
+		{"drawing too long", func(s *GameSettings) { s.DrawTime = maxRoundTime + 1 }, false},
+		{"negative drawing time", func(s *GameSettings) { s.DrawTime = -1 }, false},
+		{"guessing too long", func(s *GameSettings) { s.GuessTime = maxRoundTime + 1 }, false},
+		{"prompt writing too long", func(s *GameSettings) { s.PromptTime = maxRoundTime + 1 }, false},
+		{"no word packs", func(s *GameSettings) { s.WordPacks = nil }, false},
+		{"unknown word pack", func(s *GameSettings) { s.WordPacks = []string{"no-such-pack"} }, false},
+		{"context words every time", func(s *GameSettings) { s.ContextWordChance = 1 }, true},
+		{"context word chance over 1", func(s *GameSettings) { s.ContextWordChance = 1.1 }, false},
+		{"negative context word chance", func(s *GameSettings) { s.ContextWordChance = -0.1 }, false},
+		{"fewest max players", func(s *GameSettings) { s.MaxPlayers = MinPlayers }, true},
+		{"most max players", func(s *GameSettings) { s.MaxPlayers = maxMaxPlayers }, true},
+		{"max players too low", func(s *GameSettings) { s.MaxPlayers = MinPlayers - 1 }, false},
+		{"max players too high", func(s *GameSettings) { s.MaxPlayers = maxMaxPlayers + 1 }, false},
+		{"end on a guess", func(s *GameSettings) { s.EndOn = PLAY_GUESS }, true},
+		{"end on something else", func(s *GameSettings) { s.EndOn = "word" }, false},
+		{"unknown play order", func(s *GameSettings) { s.PlayOrder = "alphabetical" }, false},
+		{"unknown scoring", func(s *GameSettings) { s.ScoringMode = "golf" }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := DefaultSettings()
+			test.change(&settings)
+			if err := settings.Validate(); (err == nil) != test.valid {
+				t.Errorf("got %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestSettingsCanBeChangedOneAtATime(t *testing.T) {
+	settings := DefaultSettings()
+	settings.DrawTime = 45
+	g := newTestGame(t, settings, 2)
+	host := g.Players[0]
+	g.HandleMessage(testClientMessage(t, host, "", "settings", map[string]interface{}{"settings": map[string]interface{}{"rounds": 4}}))
+	if g.Settings.Rounds != 4 || g.Settings.DrawTime != 45 || g.Settings.MaxPlayers != settings.MaxPlayers {
+		t.Errorf("changing the rounds left the settings as %+v", g.Settings)
+	}
+
+	for _, change := range []map[string]interface{}{
+		{"rounds": maxRounds + 1},
+		{"wordPacks": []string{}},
+		{"drawingTime": 10},
+	} {
+		message := testClientMessage(t, host, "", "settings", map[string]interface{}{"settings": change})
+		err := message.msg.handle(g, host)
+		if protocolErr, ok := err.(*ProtocolError); !ok || protocolErr.Code != ErrCodeInvalidSettings {
+			t.Errorf("changing %v gave %v", change, err)
+		}
+	}
+	if g.Settings.Rounds != 4 || len(g.Settings.WordPacks) != 1 || g.Settings.DrawTime != 45 {
+		t.Errorf("refused changes left the settings as %+v", g.Settings)
+	}
+}
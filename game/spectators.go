@@ -0,0 +1,67 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const maxSpectators = 50
+
+// Add someone to watch the game. They can join at any point, and get a copy of themselves back like NewPlayer.
+func (g *Game) NewSpectator() (*Player, error) {
+	var spectator *Player
+	var err error
+	stopped := g.exec(func() {
+		if len(g.Spectators) >= maxSpectators {
+			err = errors.New("game has too many spectators")
+			return
+		}
+		spectatorID, uuidErr := uuid.NewRandom()
+		if uuidErr != nil {
+			log.WithError(uuidErr).Fatal("error creating UUID for NewSpectator")
+		}
+		if g.Spectators == nil {
+			g.Spectators = make(map[string]*Player)
+		}
+		spectator = &Player{
+			ID:   spectatorID.String(),
+			Name: fmt.Sprintf("Spectator %v", len(g.Spectators)),
+		}
+		g.Spectators[spectator.ID] = spectator
+	})
+	if stopped != nil {
+		return nil, stopped
+	}
+	if err != nil {
+		return nil, err
+	}
+	spectatorCopy := *spectator
+	return &spectatorCopy, nil
+}
+
+// Find the spectator with this ID, like FindPlayer.
+func (g *Game) FindSpectator(spectatorID string) *Player {
+	var spectator *Player
+	g.exec(func() {
+		spectator = g.Spectators[spectatorID]
+	})
+	return spectator
+}
+
+// Bring a spectator who has just (re)connected up to date.
+func (g *Game) catchUpSpectator(spectator *Player) {
+	switch g.Stage {
+	case GAME_STARTING:
+		g.sendPlayers()
+	case GAME_PROMPTING, GAME_RUNNING:
+		g.sendTo(spectator, "progress", g.progress())
+		g.sendLiveDrawingsSoFar(spectator)
+	case GAME_REVIEWING:
+		g.sendTo(spectator, "review", ReviewMessage{})
+	case GAME_ENDED:
+		g.sendTo(spectator, "results", ResultsMessage{Players: g.Players})
+	}
+}
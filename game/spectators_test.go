@@ -0,0 +1,45 @@
+package game
+
+import "testing"
+
+func TestSpectatorsCanWatch(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 2)
+	clients[0].send("start", "start", nil)
+	clients[0].expect("word", nil)
+	// Joining part way through catches them up on who is still playing.
+	spectator := spectateTestClient(t, g)
+	var progress ProgressMessage
+	spectator.expect("progress", &progress)
+	if progress.Stage != GAME_RUNNING || len(progress.WaitingFor) != 2 {
+		t.Errorf("spectator was sent %+v", progress)
+	}
+	var players int
+	inspectGame(g, func() { players = len(g.Players) })
+	if players != 2 {
+		t.Errorf("spectating made %v players", players)
+	}
+}
+
+func TestSpectatorsCantPlay(t *testing.T) {
+	g, clients := runTestGame(t, DefaultSettings(), 2)
+	clients[0].send("start", "start", nil)
+	clients[0].expect("word", nil)
+	spectator := spectateTestClient(t, g)
+	spectator.send("draw", "drawing", DrawingMessage{Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}}})
+	spectator.expectError(ErrCodeSpectator)
+	spectator.send("start", "start", nil)
+	spectator.expectError(ErrCodeSpectator)
+}
+
+func TestSpectatorsAreLimited(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	go g.run()
+	for i := 0; i < maxSpectators; i++ {
+		if _, err := g.NewSpectator(); err != nil {
+			t.Fatalf("spectator %v couldn't join: %v", i, err)
+		}
+	}
+	if _, err := g.NewSpectator(); err == nil {
+		t.Errorf("more than %v spectators joined", maxSpectators)
+	}
+}
@@ -3,7 +3,19 @@ package game
 type GameStage string
 
 const (
-	GAME_STARTING GameStage = "gameStarting"
-	GAME_RUNNING GameStage = "gameRunning"
-	GAME_ENDED GameStage = "gameEnded"
+	GAME_STARTING  GameStage = "gameStarting"
+	GAME_PROMPTING GameStage = "gamePrompting"
+	GAME_RUNNING   GameStage = "gameRunning"
+	GAME_REVIEWING GameStage = "gameReviewing"
+	GAME_ENDED     GameStage = "gameEnded"
 )
+
+// Where each stage is allowed to move on to.
+var stageTransitions = map[GameStage][]GameStage{
+	GAME_STARTING:  {GAME_PROMPTING, GAME_RUNNING},
+	GAME_PROMPTING: {GAME_RUNNING},
+	GAME_RUNNING:   {GAME_REVIEWING},
+	GAME_REVIEWING: {GAME_ENDED},
+	// Rematch with the same players.
+	GAME_ENDED: {GAME_STARTING},
+}
@@ -0,0 +1,240 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// Drawings can be sent as the strokes that made them rather than an image, which is a lot smaller and can be played
+// back. The server checks them over and can turn them into a PNG when an image is needed.
+
+type StrokeDrawing struct {
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Strokes []Stroke `json:"strokes"`
+}
+
+type Stroke struct {
+	// Hex colour, #rgb or #rrggbb.
+	Colour string `json:"colour"`
+	// Line width in pixels.
+	Width  int           `json:"width"`
+	Points []StrokePoint `json:"points"`
+}
+
+// x and y on the canvas, then milliseconds since the drawing was started, so points only ever go forwards in time
+// through the drawing. Kept as an array to keep messages small.
+type StrokePoint [3]int
+
+func (p StrokePoint) X() int { return p[0] }
+func (p StrokePoint) Y() int { return p[1] }
+func (p StrokePoint) T() int { return p[2] }
+
+const (
+	maxCanvasSize  = 2048
+	maxStrokeWidth = 64
+	maxStrokes     = 2000
+	// Across the whole drawing.
+	maxStrokePoints = 50000
+	// Nobody is drawing for longer than an hour.
+	maxStrokeTime = 60 * 60 * 1000
+	// Across the whole drawing, see Stroke.ink. Enough to colour in the biggest canvas with the biggest brush a few
+	// times over, which takes about a tenth of a second to render.
+	maxInk = 50000000
+)
+
+// Check a drawing makes sense before it's stored or passed on.
+func (d *StrokeDrawing) Validate() error {
+	if d.Width < 1 || d.Width > maxCanvasSize || d.Height < 1 || d.Height > maxCanvasSize {
+		return fmt.Errorf("canvas must be between 1 and %v pixels each way", maxCanvasSize)
+	}
+	if len(d.Strokes) > maxStrokes {
+		return fmt.Errorf("drawing can have at most %v strokes", maxStrokes)
+	}
+	points := 0
+	ink := 0
+	for i, stroke := range d.Strokes {
+		points += len(stroke.Points)
+		if points > maxStrokePoints {
+			return fmt.Errorf("drawing can have at most %v points", maxStrokePoints)
+		}
+		err := d.validateStroke(i, stroke)
+		if err != nil {
+			return err
+		}
+		ink += stroke.ink()
+		if ink > maxInk {
+			return errTooMuchInk
+		}
+	}
+	return nil
+}
+
+var errTooMuchInk = fmt.Errorf("drawing has too much going on, try fewer or thinner strokes")
+
+// Check the i-th stroke fits on the canvas, and carries on in time from the stroke before it.
+func (d *StrokeDrawing) validateStroke(i int, stroke Stroke) error {
+	if _, err := parseColour(stroke.Colour); err != nil {
+		return fmt.Errorf("stroke %v: %w", i, err)
+	}
+	if stroke.Width < 1 || stroke.Width > maxStrokeWidth {
+		return fmt.Errorf("stroke %v: width must be between 1 and %v", i, maxStrokeWidth)
+	}
+	if len(stroke.Points) == 0 {
+		return fmt.Errorf("stroke %v has no points", i)
+	}
+	lastTime := 0
+	if i > 0 {
+		lastTime = d.Strokes[i-1].end()
+	}
+	for _, point := range stroke.Points {
+		if point.X() < 0 || point.X() >= d.Width || point.Y() < 0 || point.Y() >= d.Height {
+			return fmt.Errorf("stroke %v goes off the canvas", i)
+		}
+		if point.T() < lastTime || point.T() > maxStrokeTime {
+			return fmt.Errorf("stroke %v has points out of order", i)
+		}
+		lastTime = point.T()
+	}
+	return nil
+}
+
+func (s Stroke) start() int {
+	return s.Points[0].T()
+}
+
+func (s Stroke) end() int {
+	return s.Points[len(s.Points)-1].T()
+}
+
+// Roughly how many pixels rendering the stroke has to look at, which is where the time rendering goes. Every stamp
+// of the brush looks at a square the width of the brush.
+func (s Stroke) ink() int {
+	radius, step := brushSpacing(s.Width)
+	stamps := 1
+	for i := 1; i < len(s.Points); i++ {
+		dx := float64(s.Points[i].X() - s.Points[i-1].X())
+		dy := float64(s.Points[i].Y() - s.Points[i-1].Y())
+		stamps += int(math.Ceil(math.Hypot(dx, dy) / step))
+	}
+	side := int(2*math.Max(radius, 0.5)) + 1
+	return stamps * side * side
+}
+
+// Read and check a drawing that has been stored as strokes.
+func ParseStrokeDrawing(data []byte) (*StrokeDrawing, error) {
+	var drawing StrokeDrawing
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&drawing)
+	if err != nil {
+		return nil, err
+	}
+	return &drawing, drawing.Validate()
+}
+
+func parseColour(hex string) (color.RGBA, error) {
+	colour := color.RGBA{A: 0xff}
+	var digits []byte
+	switch len(hex) {
+	case 4:
+		// #rgb is short for #rrggbb
+		digits = []byte{hex[1], hex[1], hex[2], hex[2], hex[3], hex[3]}
+	case 7:
+		digits = []byte(hex[1:])
+	default:
+		return colour, fmt.Errorf("colour %q isn't #rgb or #rrggbb", hex)
+	}
+	if hex[0] != '#' {
+		return colour, fmt.Errorf("colour %q isn't #rgb or #rrggbb", hex)
+	}
+	var values [3]uint8
+	for i := range values {
+		for _, digit := range digits[i*2 : i*2+2] {
+			var value byte
+			switch {
+			case digit >= '0' && digit <= '9':
+				value = digit - '0'
+			case digit >= 'a' && digit <= 'f':
+				value = digit - 'a' + 10
+			case digit >= 'A' && digit <= 'F':
+				value = digit - 'A' + 10
+			default:
+				return colour, fmt.Errorf("colour %q isn't #rgb or #rrggbb", hex)
+			}
+			values[i] = values[i]<<4 | value
+		}
+	}
+	colour.R, colour.G, colour.B = values[0], values[1], values[2]
+	return colour, nil
+}
+
+// Draw the strokes onto a white canvas. Lines are made by stamping round brushes along them, which is simple and gives
+// round ends and joins for free.
+func (d *StrokeDrawing) Render() *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, d.Width, d.Height))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, stroke := range d.Strokes {
+		colour, err := parseColour(stroke.Colour)
+		if err != nil {
+			continue
+		}
+		radius, step := brushSpacing(stroke.Width)
+		last := stroke.Points[0]
+		stampBrush(canvas, float64(last.X()), float64(last.Y()), radius, colour)
+		for _, point := range stroke.Points[1:] {
+			dx := float64(point.X() - last.X())
+			dy := float64(point.Y() - last.Y())
+			steps := int(math.Ceil(math.Hypot(dx, dy) / step))
+			for i := 1; i <= steps; i++ {
+				progress := float64(i) / float64(steps)
+				stampBrush(canvas, float64(last.X())+dx*progress, float64(last.Y())+dy*progress, radius, colour)
+			}
+			last = point
+		}
+	}
+	return canvas
+}
+
+// The brush's radius for a stroke this wide, and how far apart to stamp it. Close enough together that the stamps
+// overlap into a smooth line.
+func brushSpacing(width int) (float64, float64) {
+	radius := float64(width) / 2
+	return radius, math.Max(radius/2, 0.5)
+}
+
+func stampBrush(canvas *image.RGBA, x float64, y float64, radius float64, colour color.RGBA) {
+	// Thin lines still need to cover a pixel.
+	radius = math.Max(radius, 0.5)
+	area := image.Rect(int(x-radius), int(y-radius), int(x+radius)+1, int(y+radius)+1).Intersect(canvas.Bounds())
+	// This is where all the time goes, so compare squared distances and write the pixels directly.
+	radiusSquared := radius * radius
+	for py := area.Min.Y; py < area.Max.Y; py++ {
+		dy := float64(py) - y
+		offset := canvas.PixOffset(area.Min.X, py)
+		for px := area.Min.X; px < area.Max.X; px++ {
+			dx := float64(px) - x
+			if dx*dx+dy*dy <= radiusSquared {
+				pixel := canvas.Pix[offset : offset+4 : offset+4]
+				pixel[0], pixel[1], pixel[2], pixel[3] = colour.R, colour.G, colour.B, colour.A
+			}
+			offset += 4
+		}
+	}
+}
+
+// The drawing as a PNG, for the review and anywhere else that just wants a picture.
+func (d *StrokeDrawing) PNG() ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, d.Render())
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
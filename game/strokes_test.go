@@ -0,0 +1,72 @@
+package game
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestTooMuchInkIsRejected(t *testing.T) {
+	drawing := &StrokeDrawing{Width: maxCanvasSize, Height: maxCanvasSize}
+	// Scribbling back and forth across the canvas with the biggest brush, well within the stroke and point limits.
+	for i := 0; i < 50; i++ {
+		stroke := Stroke{Colour: "#000", Width: maxStrokeWidth}
+		for j := 0; j < 25; j++ {
+			x := 0
+			if j%2 == 1 {
+				x = maxCanvasSize - 1
+			}
+			stroke.Points = append(stroke.Points, StrokePoint{x, (i*25 + j) % maxCanvasSize, i*100 + j})
+		}
+		drawing.Strokes = append(drawing.Strokes, stroke)
+	}
+	if err := drawing.Validate(); err != errTooMuchInk {
+		t.Errorf("got %v, want %v", err, errTooMuchInk)
+	}
+
+	drawing.Strokes = drawing.Strokes[:1]
+	if err := drawing.Validate(); err != nil {
+		t.Errorf("one stroke across the canvas was rejected: %v", err)
+	}
+}
+
+func TestStrokeDrawingsAreRenderedOnceStored(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	go g.run()
+	strokes := &StrokeDrawing{Width: 40, Height: 30, Strokes: []Stroke{
+		{Colour: "#f00", Width: 4, Points: []StrokePoint{{2, 2, 0}, {30, 20, 100}}},
+	}}
+
+	var drawing *Drawing
+	stored := make(chan struct{})
+	execWithin(t, g, func() {
+		var err error
+		drawing, err = g.storeStrokeDrawing(g.Players[0], strokes, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		g.whenStored(func() { close(stored) })
+	})
+	select {
+	case <-stored:
+	case <-time.After(time.Second):
+		t.Fatal("drawing was never stored")
+	}
+	var pngRef string
+	execWithin(t, g, func() { pngRef = drawing.PNGRef })
+	if pngRef == "" {
+		t.Fatal("drawing wasn't rendered")
+	}
+	data, err := g.blobs.Get(pngRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := image.Bounds().Size(); size.X != 40 || size.Y != 30 {
+		t.Errorf("rendered drawing is %v", size)
+	}
+}
@@ -0,0 +1,112 @@
+package game
+
+import (
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	// What a guess becomes if the player runs out of time.
+	missingGuess = "(no guess)"
+	// At least this long is given to finish a round picked back up after a restart.
+	minResumeTime = 15 * time.Second
+)
+
+// Send out the current round, and start the clock on it.
+func (g *Game) beginRound() {
+	var limit time.Duration
+	if g.Stage == GAME_RUNNING {
+		limit = g.roundTimeLimit()
+	}
+	g.startRoundTimer(limit)
+	// Anything streamed in for the last round has been used by now.
+	g.recordings = nil
+	g.pruneDrawingCache()
+	g.stopLiveStrokes()
+	g.sendNextRoundToPlayers()
+	if !g.Deadline.IsZero() {
+		g.sendCountdown()
+	}
+	g.sendProgress()
+}
+
+// Start the clock on whatever players are doing now, replacing any earlier one. Zero means no time limit.
+func (g *Game) startRoundTimer(limit time.Duration) {
+	g.stopRoundTimer()
+	g.Deadline = time.Time{}
+	if limit > 0 {
+		g.Deadline = time.Now().Add(limit)
+		g.roundTimer = time.NewTimer(limit)
+	}
+}
+
+// Time's up, move on without whoever hasn't finished.
+func (g *Game) roundTimedOut() {
+	g.roundTimer = nil
+	switch g.Stage {
+	case GAME_PROMPTING:
+		g.beginPlay()
+	case GAME_RUNNING:
+		g.fillMissingPlays()
+		g.checkAndAdvanceRound()
+	}
+}
+
+func (g *Game) roundTimeLimit() time.Duration {
+	if g.expectedPlay() == PLAY_DRAWING {
+		return g.Settings.drawDuration()
+	}
+	return g.Settings.guessDuration()
+}
+
+// Pick the clock back up on a round restored from a snapshot.
+func (g *Game) resumeRoundTimer() {
+	if (g.Stage != GAME_RUNNING && g.Stage != GAME_PROMPTING) || g.Deadline.IsZero() || g.roundTimer != nil {
+		return
+	}
+	remaining := time.Until(g.Deadline)
+	if remaining < minResumeTime {
+		remaining = minResumeTime
+		g.Deadline = time.Now().Add(remaining)
+	}
+	g.roundTimer = time.NewTimer(remaining)
+}
+
+func (g *Game) stopRoundTimer() {
+	if g.roundTimer != nil {
+		g.roundTimer.Stop()
+		g.roundTimer = nil
+	}
+}
+
+// The channel the current round's time running out is sent on. Nil (so never ready) when there is no time limit.
+func (g *Game) roundTimeout() <-chan time.Time {
+	if g.roundTimer == nil {
+		return nil
+	}
+	return g.roundTimer.C
+}
+
+// Anyone who hasn't played this round by the deadline gets a blank drawing (or whatever strokes they had streamed in)
+// or no guess, so everyone else can move on.
+func (g *Game) fillMissingPlays() {
+	if g.Stage != GAME_RUNNING {
+		return
+	}
+	for _, journey := range g.Journeys {
+		if len(journey.Plays) > g.Round+1 {
+			continue
+		}
+		player := journey.Order[g.Round]
+		if g.expectedPlay() == PLAY_DRAWING {
+			drawing := g.recordedDrawing(player)
+			if drawing == nil {
+				drawing = &Drawing{Player: player}
+			}
+			journey.Plays = append(journey.Plays, drawing)
+		} else {
+			journey.Plays = append(journey.Plays, &Word{Word: missingGuess, Player: player})
+		}
+		log.WithFields(log.Fields{"gameID": g.ID, "playerID": player.ID, "round": g.Round}).Debug("player ran out of time")
+	}
+}
@@ -3,155 +3,268 @@ package game
 import (
 	"encoding/json"
 	log "github.com/sirupsen/logrus"
+	"time"
 )
 
 // Updates we can send to players
 // These include player updates, and round info
 
-type gameUpdate struct {
-	Type string `json:"type"`
-	Data []byte `json:"data"`
+// Sent as soon as a client connects, with the protocol version the rest of the conversation uses.
+type HelloMessage struct {
+	Version  int    `json:"version"`
+	PlayerID string `json:"playerID"`
+}
+
+// Who is in the lobby, who's running it, and what they've chosen to play.
+type PlayersMessage struct {
+	// Changes when the lobby is reused for a rematch.
+	JoinCode string       `json:"joinCode"`
+	Players  []*Player    `json:"players"`
+	HostID   string       `json:"hostID"`
+	Locked   bool         `json:"locked"`
+	Settings GameSettings `json:"settings"`
+}
+
+// The word to draw this round.
+type WordMessage struct {
+	Round int    `json:"round"`
+	Word  string `json:"word"`
+}
+
+// The drawing to guess this round.
+type DrawingRoundMessage struct {
+	Round int `json:"round"`
+	// Only one of these is set, depending on how the drawing was sent.
+	Drawing string          `json:"drawing"`
+	Strokes json.RawMessage `json:"strokes,omitempty"`
+	// The drawing can also be fetched from /drawing with this.
+	Ref string `json:"ref,omitempty"`
+}
+
+// All the rounds are done, time to look back at what happened.
+type ReviewMessage struct{}
+
+// Final points once everyone has finished reviewing.
+type ResultsMessage struct {
+	Players []*Player `json:"players"`
+}
+
+// How long is left on the current round.
+type CountdownMessage struct {
+	Round       int `json:"round"`
+	SecondsLeft int `json:"secondsLeft"`
+}
+
+// Who is still playing the current round (or writing their prompt), and when it will be moved on without them.
+type ProgressMessage struct {
+	Stage GameStage `json:"gameStage"`
+	Round int       `json:"round"`
+	// IDs of the players still to play.
+	WaitingFor []string `json:"waitingFor"`
+	// Left out when the round has no time limit.
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	SecondsLeft int        `json:"secondsLeft,omitempty"`
+}
+
+// The player's message was handled.
+type AckMessage struct {
+	RequestID string `json:"requestID,omitempty"`
+	Type      string `json:"type"`
+}
+
+// Something the player asked for couldn't be done.
+type ErrorMessage struct {
+	RequestID string `json:"requestID,omitempty"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+// How long the game waits for the hub to take a message before giving up on it.
+const hubSendWait = time.Second
+
+func (g *Game) broadcast(msgType string, data interface{}) {
+	messageBytes, err := encodeMessage(msgType, data)
+	if err != nil {
+		log.WithError(err).Error("problem marshalling game update to JSON")
+		return
+	}
+	select {
+	case g.Hub.broadcasts <- &GameMessage{Target: nil, Message: &messageBytes}:
+	default:
+		log.WithField("type", msgType).Error("could not dispatch broadcast")
+	}
+}
+
+func (g *Game) sendTo(player *Player, msgType string, data interface{}) {
+	messageBytes, err := encodeMessage(msgType, data)
+	if err != nil {
+		log.WithError(err).Error("problem marshalling game update to JSON")
+		return
+	}
+	message := &GameMessage{Target: player, Message: &messageBytes}
+	select {
+	case g.Hub.messages <- message:
+		return
+	default:
+	}
+	// The hub is behind. Wait for it rather than lose the message, but not for ever, as it could be waiting on us.
+	select {
+	case g.Hub.messages <- message:
+	case <-time.After(hubSendWait):
+		log.WithField("type", msgType).Error("could not dispatch message")
+	}
+}
+
+// Like sendTo, for messages that are fine to lose when the hub or the player can't keep up.
+func (g *Game) sendLossy(player *Player, msgType string, data interface{}) {
+	messageBytes, err := encodeMessage(msgType, data)
+	if err != nil {
+		log.WithError(err).Error("problem marshalling game update to JSON")
+		return
+	}
+	select {
+	case g.Hub.liveMessages <- &GameMessage{Target: player, Message: &messageBytes}:
+	default:
+		log.WithField("type", msgType).Debug("dropped message")
+	}
 }
 
 func (g *Game) sendPlayers() {
-	players, _ := json.Marshal(g.Players)
-	gameUpdate, _ := json.Marshal(gameUpdate{
-		Type: "players",
-		Data: players,
+	g.broadcast("players", PlayersMessage{
+		JoinCode: g.JoinCode,
+		Players:  g.Players,
+		HostID:   g.HostID,
+		Locked:   g.IsLocked(),
+		Settings: g.Settings,
 	})
-	g.Hub.broadcasts <- &GameMessage{
-		Target:  nil,
-		Message: &gameUpdate,
-	}
 }
 
 func (g *Game) sendResults() {
-	players, _ := json.Marshal(g.Players)
-	gameUpdate, _ := json.Marshal(gameUpdate{
-		Type: "results",
-		Data: players,
-	})
-	g.Hub.broadcasts <- &GameMessage{
-		Target:  nil,
-		Message: &gameUpdate,
-	}
+	g.broadcast("results", ResultsMessage{Players: g.Players})
+}
+
+func (g *Game) sendAck(player *Player, requestID string, msgType string) {
+	g.sendTo(player, "ack", AckMessage{RequestID: requestID, Type: msgType})
+}
+
+func (g *Game) sendError(player *Player, requestID string, err *ProtocolError) {
+	g.sendTo(player, "error", ErrorMessage{RequestID: requestID, Code: err.Code, Message: err.Message})
 }
 
 // Give each player their appropriate words to draw
 func (g *Game) sendNextRoundToPlayers() {
-	if g.Round == g.Limit {
-		// Game Over!
-		update := gameUpdate{
-			Type: "review",
-			Data: nil,
-		}
-		messageBytes, err := json.Marshal(update)
-		if err != nil {
-			log.WithError(err).Error("problem marshalling game update to JSON")
-			return
-		}
-		gameMessage := &GameMessage{
-			Target:  nil,
-			Message: &messageBytes,
-		}
-		select {
-		case g.Hub.broadcasts <- gameMessage:
-		default:
-			log.Error("Could not dispatch message")
-		}
+	if g.Stage == GAME_REVIEWING {
+		// Game Over! The review fetches the drawings, so wait until they've all been stored.
+		g.whenStored(func() {
+			if g.Stage == GAME_REVIEWING {
+				g.broadcast("review", ReviewMessage{})
+			}
+		})
 		return
 	}
 	for _, journey := range g.Journeys {
-		// Chose stage in journey according to round.
-		var update gameUpdate
-		if g.Round%2 == 0 {
-			update = gameUpdate{
-				Type: "word",
-				Data: []byte(journey.Plays[g.Round].GetPlay()),
-			}
-		} else {
-			update = gameUpdate{
-				Type: "drawing",
-				Data: []byte(journey.Plays[g.Round].GetPlay()),
-			}
-		}
-		messageBytes, err := json.Marshal(update)
-		if err != nil {
-			log.WithError(err).Error("problem marshalling game update to JSON")
+		g.sendRound(journey.Order[g.Round], journey)
+	}
+}
+
+// Send the player what they need to play on this journey in the current round. Drawings may need fetching first, by
+// which time the game could have moved on.
+func (g *Game) sendRound(player *Player, journey *WordJourney) {
+	play := journey.Plays[g.Round]
+	drawing, isDrawing := play.(*Drawing)
+	if !isDrawing {
+		g.sendTo(player, "word", WordMessage{Round: g.Round, Word: play.GetPlay()})
+		return
+	}
+	round := g.Round
+	g.withDrawingData(drawing, func(data string) {
+		if g.Stage != GAME_RUNNING || g.Round != round {
 			return
 		}
-		msg := &GameMessage{
-			Target:  journey.Order[g.Round],
-			Message: &messageBytes,
-		}
-		select {
-		case g.Hub.messages <- msg:
-		default:
-			log.Error("could not dispatch message")
+		message := DrawingRoundMessage{Round: round, Ref: drawing.Ref}
+		if drawing.Format == DRAWING_STROKES {
+			if data != "" {
+				message.Strokes = json.RawMessage(data)
+			}
+		} else {
+			message.Drawing = data
 		}
-	}
+		g.sendTo(player, "drawing", message)
+	})
 }
 
-// TODO: Fix duplication here
+// Let a player who has just reconnected catch back up.
 func (g *Game) reconnectPlayer(player *Player) {
-	if g.Stage == GAME_ENDED {
-		g.sendResults()
+	if _, spectating := g.Spectators[player.ID]; spectating {
+		// Already caught up when their connection was reported.
 		return
 	}
-	if g.Stage == GAME_STARTING {
+	switch g.Stage {
+	case GAME_STARTING:
 		g.sendPlayers()
-		return
-	}
-	if g.Round == g.Limit {
-		// Game Over!
-		update := gameUpdate{
-			Type: "review",
-			Data: nil,
+	case GAME_PROMPTING:
+		if _, written := g.Prompts[player.ID]; !written {
+			g.sendTo(player, "writePrompt", WritePromptMessage{})
 		}
-		messageBytes, err := json.Marshal(update)
-		if err != nil {
-			log.WithError(err).Error("problem marshalling game update to JSON")
-			return
+		g.sendTo(player, "progress", g.progress())
+	case GAME_RUNNING:
+		journey := g.journeyForPlayer(player)
+		if journey != nil {
+			g.sendRound(player, journey)
+			log.WithField("playerID", player.ID).Debug("sent reconnection msg")
+		}
+		if recording := g.currentRecording(player); recording != nil {
+			g.sendTo(player, "recording", RecordingMessage{Round: g.Round, Drawing: recording.Drawing})
 		}
-		select {
-		case g.Hub.messages <- &GameMessage{
-			Target:  player,
-			Message: &messageBytes,
-		}:
-		default:
-			log.Error("could not dispatch reconnection message")
+		if !g.isWaitingFor(player) {
+			// Finished, so they've been watching everyone else draw.
+			g.sendLiveDrawingsSoFar(player)
 		}
+		g.sendTo(player, "progress", g.progress())
+	case GAME_REVIEWING:
+		g.whenStored(func() {
+			g.sendTo(player, "review", ReviewMessage{})
+		})
+	case GAME_ENDED:
+		g.sendTo(player, "results", ResultsMessage{Players: g.Players})
+	}
+}
+
+// Let everyone know how long is left on the current round.
+func (g *Game) sendCountdown() {
+	if g.Deadline.IsZero() {
 		return
 	}
-	for _, journey := range g.Journeys {
-		if journey.Order[g.Round].ID == player.ID {
-			var update gameUpdate
-			if g.Round%2 == 0 {
-				update = gameUpdate{
-					Type: "word",
-					Data: []byte(journey.Plays[g.Round].GetPlay()),
-				}
-			} else {
-				update = gameUpdate{
-					Type: "drawing",
-					Data: []byte(journey.Plays[g.Round].GetPlay()),
-				}
-			}
-			messageBytes, err := json.Marshal(update)
-			if err != nil {
-				log.WithError(err).Error("problem marshalling game update to JSON")
-				return
-			}
-			msg := &GameMessage{
-				Target:  player,
-				Message: &messageBytes,
-			}
-			select {
-			case g.Hub.messages <- msg:
-				log.WithField("msg", msg).Debug("sent reconnection msg")
-			default:
-				log.Error("could not dispatch reconnection message")
-			}
-		}
+	g.broadcast("countdown", CountdownMessage{Round: g.Round, SecondsLeft: g.secondsLeft()})
+}
+
+func (g *Game) secondsLeft() int {
+	secondsLeft := int(time.Until(g.Deadline).Round(time.Second) / time.Second)
+	if secondsLeft < 0 {
+		secondsLeft = 0
+	}
+	return secondsLeft
+}
+
+// Who we're still waiting on, worked out the same way checkAndAdvanceRound decides whether to move on.
+func (g *Game) progress() ProgressMessage {
+	waitingFor := make([]string, 0)
+	for _, player := range g.waitingFor() {
+		waitingFor = append(waitingFor, player.ID)
+	}
+	progress := ProgressMessage{Stage: g.Stage, Round: g.Round, WaitingFor: waitingFor}
+	if !g.Deadline.IsZero() {
+		deadline := g.Deadline
+		progress.Deadline = &deadline
+		progress.SecondsLeft = g.secondsLeft()
+	}
+	return progress
+}
+
+// Let everyone know who is still playing, so those who have finished aren't left staring at nothing.
+func (g *Game) sendProgress() {
+	if g.Stage != GAME_PROMPTING && g.Stage != GAME_RUNNING {
+		return
 	}
+	g.broadcast("progress", g.progress())
 }
@@ -0,0 +1,38 @@
+package game
+
+import "testing"
+
+func TestProgressShowsWhoIsStillPlaying(t *testing.T) {
+	settings := DefaultSettings()
+	settings.DrawTime = 0
+	_, clients := runTestGame(t, settings, 3)
+	watcher := clients[2]
+	clients[0].send("start", "start", nil)
+	for i, c := range clients[:2] {
+		c.expect("word", nil)
+		c.send("draw", "drawing", DrawingMessage{Strokes: &StrokeDrawing{Width: 100, Height: 100, Strokes: []Stroke{testStroke(0)}}})
+		var progress ProgressMessage
+		for len(progress.WaitingFor) != len(clients)-i-1 {
+			watcher.expect("progress", &progress)
+		}
+		for _, playerID := range progress.WaitingFor {
+			if playerID == c.player.ID {
+				t.Errorf("still waiting for %v after they drew", playerID)
+			}
+		}
+		if progress.Deadline != nil || progress.SecondsLeft != 0 {
+			t.Errorf("untimed round has a deadline: %+v", progress)
+		}
+	}
+}
+
+func TestProgressHasTheDeadlineForTimedRounds(t *testing.T) {
+	g := newTestGame(t, DefaultSettings(), 2)
+	if err := g.StartGame(); err != nil {
+		t.Fatal(err)
+	}
+	progress := g.progress()
+	if progress.Deadline == nil || !progress.Deadline.Equal(g.Deadline) || progress.SecondsLeft <= 0 {
+		t.Errorf("timed round's progress is %+v", progress)
+	}
+}
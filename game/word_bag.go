@@ -0,0 +1,101 @@
+package game
+
+import "math/rand"
+
+// Bags are split into pools, so running out of one kind of word doesn't put the others back in the bag.
+const (
+	bagPoolWords   = "words"
+	bagPoolContext = "context"
+)
+
+// A WordBag hands out words without repeating any until every one has been served, like drawing from a bag. Once a
+// pool is empty it's refilled. It's seeded, so the same seed and words always come out in the same order, even if the
+// bag was saved in a snapshot and read back in between draws.
+type WordBag struct {
+	Seed int64 `json:"seed"`
+	// How many words have been drawn altogether, each draw gets its own random numbers from the seed and this.
+	Draws int `json:"draws"`
+	// Words served from each pool since it was last refilled, in order.
+	Pools map[string][]string `json:"pools"`
+}
+
+func newWordBag(seed int64) *WordBag {
+	return &WordBag{Seed: seed, Pools: make(map[string][]string)}
+}
+
+// Pick a word from the pool that hasn't been served yet. The words can change between draws, as packs are chosen or
+// reloaded.
+func (b *WordBag) draw(pool string, words []string) string {
+	return b.drawWeighted(pool, words, nil)
+}
+
+// Like draw, but some words are more likely to come out than others. Nil weights means they're all equally likely.
+func (b *WordBag) drawWeighted(pool string, words []string, weights []float64) string {
+	if len(words) == 0 {
+		return ""
+	}
+	if b.Pools == nil {
+		b.Pools = make(map[string][]string)
+	}
+	served := make(map[string]bool)
+	for _, word := range b.Pools[pool] {
+		served[word] = true
+	}
+	remaining := make([]string, 0, len(words))
+	remainingWeights := make([]float64, 0, len(words))
+	for i, word := range words {
+		if !served[word] {
+			remaining = append(remaining, word)
+			remainingWeights = append(remainingWeights, weightAt(weights, i))
+		}
+	}
+	if len(remaining) == 0 {
+		// Everything in this pool has been served, refill it.
+		b.Pools[pool] = nil
+		return b.drawWeighted(pool, words, weights)
+	}
+	rng := rand.New(rand.NewSource(b.Seed + int64(b.Draws)))
+	word := remaining[weightedChoice(remainingWeights, rng.Float64)]
+	b.Draws++
+	b.Pools[pool] = append(b.Pools[pool], word)
+	return word
+}
+
+// Pick a word from the bag's pool if there is a bag, otherwise at random.
+func pickWord(pool string, words []string, bag *WordBag, rng *rand.Rand) string {
+	if bag != nil {
+		return bag.draw(pool, words)
+	}
+	return words[rng.Intn(len(words))]
+}
+
+// Pick a word from the bag's pool if there is a bag, otherwise at random, with some words more likely than others.
+func pickWeightedWord(pool string, words []string, weights []float64, bag *WordBag, rng *rand.Rand) string {
+	if bag != nil {
+		return bag.drawWeighted(pool, words, weights)
+	}
+	return words[weightedChoice(weights, rng.Float64)]
+}
+
+func weightAt(weights []float64, i int) float64 {
+	if weights == nil {
+		return 1
+	}
+	return weights[i]
+}
+
+// Pick an index with chance in proportion to its weight, using random to get a float >= 0, < 1.
+func weightedChoice(weights []float64, random func() float64) int {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	target := random() * total
+	for i, weight := range weights {
+		if target < weight {
+			return i
+		}
+		target -= weight
+	}
+	return len(weights) - 1
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var bagWords = []string{"Cat", "Dog", "Fish", "Horse", "Mouse", "Owl", "Snake", "Tiger"}
+
+func drawAll(bag *WordBag, pool string, words []string, count int) []string {
+	drawn := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		drawn = append(drawn, bag.draw(pool, words))
+	}
+	return drawn
+}
+
+func TestWordBagIsReproducible(t *testing.T) {
+	first := drawAll(newWordBag(42), bagPoolWords, bagWords, 20)
+	second := drawAll(newWordBag(42), bagPoolWords, bagWords, 20)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same seed gave %v then %v", first, second)
+	}
+	if other := drawAll(newWordBag(43), bagPoolWords, bagWords, 20); reflect.DeepEqual(first, other) {
+		t.Errorf("different seeds both gave %v", first)
+	}
+}
+
+func TestWordBagIsReproducibleAcrossSnapshots(t *testing.T) {
+	want := drawAll(newWordBag(7), bagPoolWords, bagWords, 12)
+
+	bag := newWordBag(7)
+	got := drawAll(bag, bagPoolWords, bagWords, 5)
+	data, err := json.Marshal(bag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &WordBag{}
+	if err = json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, drawAll(restored, bagPoolWords, bagWords, 7)...)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("restored bag gave %v, want %v", got, want)
+	}
+}
+
+func TestWordBagServesEveryWordBeforeRepeating(t *testing.T) {
+	drawn := drawAll(newWordBag(1), bagPoolWords, bagWords, len(bagWords))
+	seen := make(map[string]bool)
+	for _, word := range drawn {
+		if seen[word] {
+			t.Fatalf("%q served twice before the bag was empty: %v", word, drawn)
+		}
+		seen[word] = true
+	}
+}
+
+func TestWordBagPoolsAreRefilledSeparately(t *testing.T) {
+	bag := newWordBag(3)
+	templates := []string{"{player}'s Pet", "{player}'s Hat"}
+	words := drawAll(bag, bagPoolWords, bagWords, 3)
+	// Empty the context pool a couple of times over, which shouldn't put any words back.
+	drawAll(bag, bagPoolContext, templates, 5)
+	words = append(words, drawAll(bag, bagPoolWords, bagWords, len(bagWords)-3)...)
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if seen[word] {
+			t.Fatalf("%q served twice, refilling the context pool refilled the words: %v", word, words)
+		}
+		seen[word] = true
+	}
+}
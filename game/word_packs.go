@@ -0,0 +1,267 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// A WordPack is a themed set of starting words the host can pick from.
+type WordPack struct {
+	// Taken from the file name, this is what settings refer to the pack by.
+	ID         string   `json:"id" yaml:"-"`
+	Name       string   `json:"name" yaml:"name"`
+	Language   string   `json:"language" yaml:"language"`
+	Category   string   `json:"category" yaml:"category"`
+	Difficulty string   `json:"difficulty" yaml:"difficulty"`
+	NSFW       bool     `json:"nsfw" yaml:"nsfw"`
+	Words      []string `json:"words" yaml:"words"`
+	// Context word templates (see context_words.go), and how likely they are to be picked compared to other packs'.
+	Context       []string `json:"context" yaml:"context"`
+	ContextWeight float64  `json:"contextWeight" yaml:"contextWeight"`
+}
+
+// WordPackInfo describes a pack without listing every word in it.
+type WordPackInfo struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Language   string `json:"language"`
+	Category   string `json:"category"`
+	Difficulty string `json:"difficulty"`
+	NSFW       bool   `json:"nsfw"`
+	WordCount  int    `json:"wordCount"`
+	// Number of context word templates.
+	ContextCount int `json:"contextCount"`
+}
+
+var (
+	wordPacksMu sync.RWMutex
+	wordPacks   = builtInWordPacks()
+)
+
+func builtInWordPacks() map[string]*WordPack {
+	return map[string]*WordPack{
+		defaultWordPack: {
+			ID:            defaultWordPack,
+			Name:          "Classic",
+			Language:      "en",
+			Category:      "general",
+			Difficulty:    "medium",
+			Words:         wordList,
+			Context:       contextList,
+			ContextWeight: 1,
+		},
+	}
+}
+
+// Load every word pack in the directory, replacing any loaded before. Packs are either JSON or YAML files in the shape
+// of WordPack, or text files with one word per line, "context: <template>" lines for context words and
+// "# key: value" lines for the details. The built in packs are always available, and a missing directory just means
+// there's nothing more to add.
+func LoadWordPacks(dir string) error {
+	packs := builtInWordPacks()
+	for _, pack := range packs {
+		for _, template := range pack.Context {
+			if err := validateContextTemplate(template); err != nil {
+				return fmt.Errorf("built in word pack %v: %w", pack.ID, err)
+			}
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		pack, err := readWordPack(path)
+		if err != nil {
+			return fmt.Errorf("could not load word pack %v: %w", path, err)
+		}
+		if pack == nil {
+			continue
+		}
+		if _, taken := packs[pack.ID]; taken {
+			return fmt.Errorf("more than one word pack called %q", pack.ID)
+		}
+		packs[pack.ID] = pack
+	}
+	wordPacksMu.Lock()
+	wordPacks = packs
+	wordPacksMu.Unlock()
+	log.WithField("count", len(packs)).Info("word packs loaded")
+	return nil
+}
+
+// Read a word pack file, or nothing if it isn't a kind of file packs are kept in.
+func readWordPack(path string) (*WordPack, error) {
+	extension := filepath.Ext(path)
+	switch extension {
+	case ".json", ".yaml", ".yml", ".txt":
+	default:
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pack := &WordPack{}
+	switch extension {
+	case ".json":
+		// As strict as YAML, so a misspelt field isn't quietly ignored.
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(pack)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, pack)
+	default:
+		err = parseTextWordPack(data, pack)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pack.ID = strings.TrimSuffix(filepath.Base(path), extension)
+	if pack.Name == "" {
+		pack.Name = pack.ID
+	}
+	words := make([]string, 0, len(pack.Words))
+	for _, word := range pack.Words {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no words")
+	}
+	pack.Words = words
+	for _, template := range pack.Context {
+		if err = validateContextTemplate(template); err != nil {
+			return nil, err
+		}
+	}
+	if pack.ContextWeight < 0 {
+		return nil, fmt.Errorf("negative context weight")
+	}
+	if pack.ContextWeight == 0 {
+		pack.ContextWeight = 1
+	}
+	return pack, nil
+}
+
+func parseTextWordPack(data []byte, pack *WordPack) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "context:") {
+			pack.Context = append(pack.Context, strings.TrimSpace(strings.TrimPrefix(line, "context:")))
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			pack.Words = append(pack.Words, line)
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(line, "#"), ":", 2)
+		if len(parts) != 2 {
+			// Just a comment.
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "name":
+			pack.Name = value
+		case "language":
+			pack.Language = value
+		case "category":
+			pack.Category = value
+		case "difficulty":
+			pack.Difficulty = value
+		case "context-weight":
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid context-weight value %q", value)
+			}
+			pack.ContextWeight = weight
+		case "nsfw":
+			nsfw, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid nsfw value %q", value)
+			}
+			pack.NSFW = nsfw
+		}
+	}
+	return scanner.Err()
+}
+
+// Details of every word pack available, sorted by ID.
+func ListWordPacks() []WordPackInfo {
+	wordPacksMu.RLock()
+	defer wordPacksMu.RUnlock()
+	infos := make([]WordPackInfo, 0, len(wordPacks))
+	for _, pack := range wordPacks {
+		infos = append(infos, WordPackInfo{
+			ID:           pack.ID,
+			Name:         pack.Name,
+			Language:     pack.Language,
+			Category:     pack.Category,
+			Difficulty:   pack.Difficulty,
+			NSFW:         pack.NSFW,
+			WordCount:    len(pack.Words),
+			ContextCount: len(pack.Context),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Whether a pack exists, and if it's NSFW.
+func lookUpWordPack(id string) (found bool, nsfw bool) {
+	wordPacksMu.RLock()
+	defer wordPacksMu.RUnlock()
+	pack, found := wordPacks[id]
+	return found, found && pack.NSFW
+}
+
+// All the context word templates in the chosen packs, along with each one's pack's weight. NSFW packs are left out
+// unless they're allowed, in case a pack was marked NSFW after it was chosen.
+func contextFromPacks(ids []string, allowNSFW bool) ([]string, []float64) {
+	wordPacksMu.RLock()
+	defer wordPacksMu.RUnlock()
+	templates := make([]string, 0)
+	weights := make([]float64, 0)
+	for _, id := range ids {
+		if pack, found := wordPacks[id]; found && (allowNSFW || !pack.NSFW) {
+			for _, template := range pack.Context {
+				templates = append(templates, template)
+				weights = append(weights, pack.ContextWeight)
+			}
+		}
+	}
+	return templates, weights
+}
+
+// All the words in the chosen packs. Packs that have since been removed, or are NSFW when that isn't allowed, are
+// skipped.
+func wordsFromPacks(ids []string, allowNSFW bool) []string {
+	wordPacksMu.RLock()
+	defer wordPacksMu.RUnlock()
+	words := make([]string, 0)
+	for _, id := range ids {
+		if pack, found := wordPacks[id]; found && (allowNSFW || !pack.NSFW) {
+			words = append(words, pack.Words...)
+		}
+	}
+	return words
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWordPacksReadsYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer LoadWordPacks(dir + "-missing")
+	files := map[string]string{
+		"animals.yaml": "name: Animals\nlanguage: en\ncontextWeight: 2\nwords:\n  - Cat\n  - Dog\ncontext:\n  - \"{player}'s Pet\"\n",
+		"rude.yml":     "name: Rude\nnsfw: true\nwords: [Bum]\ncontext: [\"{player}'s Bum\"]\n",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := LoadWordPacks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	animals := wordsFromPacks([]string{"animals"}, false)
+	if len(animals) != 2 || animals[0] != "Cat" || animals[1] != "Dog" {
+		t.Errorf("animals pack has words %v", animals)
+	}
+	templates, weights := contextFromPacks([]string{"animals"}, false)
+	if len(templates) != 1 || weights[0] != 2 {
+		t.Errorf("animals pack has context %v weighted %v", templates, weights)
+	}
+	for _, info := range ListWordPacks() {
+		if info.ID == "rude" && (!info.NSFW || info.Name != "Rude") {
+			t.Errorf("rude pack listed as %+v", info)
+		}
+	}
+
+	if words := wordsFromPacks([]string{"animals", "rude"}, false); len(words) != 2 {
+		t.Errorf("NSFW words used when they weren't allowed: %v", words)
+	}
+	if templates, _ := contextFromPacks([]string{"rude"}, false); len(templates) != 0 {
+		t.Errorf("NSFW context used when it wasn't allowed: %v", templates)
+	}
+	if words := wordsFromPacks([]string{"animals", "rude"}, true); len(words) != 3 {
+		t.Errorf("NSFW words left out when they were allowed: %v", words)
+	}
+
+	settings := DefaultSettings()
+	settings.WordPacks = []string{"rude"}
+	if err := settings.Validate(); err == nil {
+		t.Error("NSFW pack chosen without allowing NSFW packs")
+	}
+	settings.AllowNSFW = true
+	if err := settings.Validate(); err != nil {
+		t.Errorf("NSFW pack rejected when allowed: %v", err)
+	}
+}
+
+func TestLoadWordPacksRejectsUnknownFields(t *testing.T) {
+	packs := map[string]string{
+		"typo.yaml": "name: Typo\nwrods: [Cat]\n",
+		"typo.json": `{"name": "Typo", "words": ["Cat"], "nfsw": true}`,
+	}
+	for name, contents := range packs {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "packs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			defer LoadWordPacks(dir + "-missing")
+			err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := LoadWordPacks(dir); err == nil {
+				t.Error("pack with a misspelt field loaded")
+			}
+		})
+	}
+}
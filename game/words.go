@@ -1,41 +1,41 @@
 package game
 
-import "fmt"
+// The built in pack, made from wordList.
+const defaultWordPack = "classic"
 
-// Don't deconflict generated words per game, it could be fun to see different approaches to the same term...
-func generateWord(player *Player, players []*Player) string {
-	// Use a context word 10% of the time
-	if len(players) >= 2 && randomInt(0, 101) < 10 {
-		return generateContextWord(player, players)
-	}
-	// Otherwise use a random phrase
-	choice := randomInt(0, len(wordList))
-	return wordList[choice]
-}
-
-// Context words use other player's names.
-func generateContextWord(player *Player, players []*Player) string {
-	otherPlayers := make([]*Player, 0)
-	for _, playa := range players {
-		if player.ID != playa.ID {
-			otherPlayers = append(otherPlayers, playa)
+// Words are only deconflicted per game if it has a word bag, otherwise it could be fun to see different approaches to
+// the same term...
+func generateWord(g *Game, player *Player) string {
+	// Use a context word some of the time
+	if len(g.Players) >= 2 && g.rng.Float64() < g.Settings.ContextWordChance {
+		if word, ok := generateContextWord(g, player); ok {
+			return word
 		}
 	}
-	chosenPlayer := otherPlayers[randomInt(0, len(otherPlayers))]
-	chosenContext := contextList[randomInt(0, len(contextList))]
-	return fmt.Sprintf(chosenContext, chosenPlayer.Name)
+	// Otherwise use a random phrase from the chosen packs
+	words := wordsFromPacks(g.Settings.WordPacks, g.Settings.AllowNSFW)
+	if len(words) == 0 {
+		words = wordList
+	}
+	return pickWord(bagPoolWords, words, g.WordBag, g.rng)
 }
 
 var contextList = []string{
-	"A Portrait of %v",
-	"%v's Favourite Hobby",
-	"%v's Favourite Film",
-	"%v's Biggest Fear",
-	"%v's Favourite Animal",
-	"%v's Favourite Food",
-	"Something %v Would Hate",
-	"%v's Idea of Hell",
-	"%v in Their Happy Place",
+	"A Portrait of {player}",
+	"{player}'s Favourite Hobby",
+	"{player}'s Favourite Film",
+	"{player}'s Biggest Fear",
+	"{player}'s Favourite Animal",
+	"{player}'s Favourite Food",
+	"Something {player} Would Hate",
+	"{player}'s Idea of Hell",
+	"{player} in Their Happy Place",
+	"{player} and {otherPlayer} on a First Date",
+	"{player} Arguing With {otherPlayer}",
+	"{host}'s Rules",
+	"A Table for {playerCount}",
+	"{player} on {date}",
+	"{player} Enjoying {season}",
 }
 
 var wordList = []string{
@@ -6,4 +6,5 @@ require (
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/websocket v1.4.2
 	github.com/sirupsen/logrus v1.6.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -2,27 +2,88 @@ package main
 
 import (
 	"drawl-server/api"
+	"drawl-server/game"
 	"flag"
 	log "github.com/sirupsen/logrus"
 	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 var addr = flag.String("addr", ":8080", "http service address")
+var archiveDir = flag.String("archive", "games", "directory to keep finished games in, empty to not keep them")
+var packDir = flag.String("packs", "packs", "directory to load word packs from, reloaded on SIGHUP")
+var drawingDir = flag.String("drawings", "drawings", "directory to keep drawings in, empty to keep them in memory")
+var s3Endpoint = flag.String("s3-endpoint", "https://s3.amazonaws.com", "S3 (or compatible) service to keep drawings in")
+var s3Bucket = flag.String("s3-bucket", "", "S3 bucket to keep drawings in instead of -drawings, credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+var s3Region = flag.String("s3-region", "us-east-1", "region of the S3 bucket")
 
 func main() {
 	flag.Parse()
 	rand.Seed(time.Now().UnixNano())
 	log.SetLevel(log.DebugLevel)
 
-	http.HandleFunc("/game", api.HandleNewGame)
-	http.HandleFunc("/join", api.HandleJoinGame)
-	http.HandleFunc("/review", api.HandleGetGameReview)
-	http.HandleFunc("/results", api.HandleGetGameResults)
-	http.HandleFunc("/ws", api.HandleWS)
-	err := http.ListenAndServe(*addr, nil)
+	err := game.LoadWordPacks(*packDir)
+	if err != nil {
+		log.WithError(err).Fatal("could not load word packs")
+	}
+	go reloadWordPacksOnHangup()
+
+	var archive game.GameArchive
+	if *archiveDir != "" {
+		fileArchive, err := game.NewFileArchive(*archiveDir)
+		if err != nil {
+			log.WithError(err).Fatal("could not open game archive")
+		}
+		archive = fileArchive
+	}
+	blobs, err := openBlobStore()
+	if err != nil {
+		log.WithError(err).Fatal("could not open drawing store")
+	}
+	store := game.NewMemoryStore()
+	if archive != nil {
+		err := game.RestoreGames(store, archive, blobs)
+		if err != nil {
+			log.WithError(err).Error("could not restore running games")
+		}
+	}
+	server := api.NewServer(store, archive, blobs)
+	http.HandleFunc("/game", server.HandleNewGame)
+	http.HandleFunc("/join", server.HandleJoinGame)
+	http.HandleFunc("/spectate", server.HandleSpectateGame)
+	http.HandleFunc("/review", server.HandleGetGameReview)
+	http.HandleFunc("/results", server.HandleGetGameResults)
+	http.HandleFunc("/drawing", server.HandleGetDrawing)
+	http.HandleFunc("/ws", server.HandleWS)
+	http.HandleFunc("/packs", server.HandleGetWordPacks)
+	err = http.ListenAndServe(*addr, nil)
 	if err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
 }
+
+func openBlobStore() (game.BlobStore, error) {
+	if *s3Bucket != "" {
+		return game.NewS3BlobStore(*s3Endpoint, *s3Bucket, *s3Region, os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	}
+	if *drawingDir == "" {
+		return game.NewMemoryBlobStore(), nil
+	}
+	return game.NewFileBlobStore(*drawingDir)
+}
+
+func reloadWordPacksOnHangup() {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		// Keep the packs we've got if the new ones are broken.
+		err := game.LoadWordPacks(*packDir)
+		if err != nil {
+			log.WithError(err).Error("could not reload word packs")
+		}
+	}
+}